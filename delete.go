@@ -0,0 +1,96 @@
+package korm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func (s *DB) Delete(model any) (int64, error) {
+	return s.DeleteCtx(context.Background(), model)
+}
+
+func (s *DB) DeleteCtx(ctx context.Context, model any) (int64, error) {
+	return deleteRows(ctx, s, s.conn, model)
+}
+
+func (tx DBTx) Delete(model any) (int64, error) {
+	return tx.DeleteCtx(context.Background(), model)
+}
+
+func (tx DBTx) DeleteCtx(ctx context.Context, model any) (int64, error) {
+	return deleteRows(ctx, tx.Driver, tx.Tx, model)
+}
+
+// deleteRows deletes the rows whose primary keys match model, a struct
+// pointer or a slice of them. A slice is deleted with a single
+// DELETE ... WHERE pk = ANY($1) when the primary key has one column.
+func deleteRows(ctx context.Context, d Driver, conn dbConn, model any) (int64, error) {
+	t, elems, err := modelValues(model)
+	if err != nil {
+		return 0, err
+	}
+	if len(elems) == 0 {
+		return 0, nil
+	}
+	field, err := lookupField(d, t)
+	if err != nil {
+		return 0, err
+	}
+	if len(field.PrimaryKeys) == 0 {
+		return 0, fmt.Errorf("table %s has no primary key", field.TableName)
+	}
+
+	keys := make([][]any, len(elems))
+	for i, elem := range elems {
+		row, err := buildInsertRow(d, field, t, elem)
+		if err != nil {
+			return 0, err
+		}
+		keys[i] = field.columnValues(row, field.PrimaryKeys)
+	}
+
+	if reflect.ValueOf(model).Kind() == reflect.Ptr {
+		cond, err := field.pkCondition(1)
+		if err != nil {
+			return 0, err
+		}
+		return exec(ctx, d, conn, fmt.Sprintf("DELETE FROM %s WHERE %s", field.TableName, cond), keys[0]...)
+	}
+
+	if len(field.PrimaryKeys) == 1 {
+		pks := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(keys[0][0])), 0, len(keys))
+		for _, key := range keys {
+			pks = reflect.Append(pks, reflect.ValueOf(key[0]))
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ANY($1)", field.TableName, field.PrimaryKeys[0])
+		return exec(ctx, d, conn, query, pks.Interface())
+	}
+
+	// Composite keys are matched with (a, b) IN (($1, $2), ...), split so
+	// that each statement stays within the bind parameter limit.
+	var affected int64
+	chunkSize := maxQueryParams / len(field.PrimaryKeys)
+	for start := 0; start < len(keys); start += chunkSize {
+		chunk := keys[start:min(start+chunkSize, len(keys))]
+		tuples := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*len(field.PrimaryKeys))
+		for i, key := range chunk {
+			params := make([]string, len(key))
+			for j := range key {
+				params[j] = fmt.Sprintf("$%d", len(args)+j+1)
+			}
+			tuples[i] = "(" + strings.Join(params, ", ") + ")"
+			args = append(args, key...)
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE (%s) IN (%s)",
+			field.TableName, strings.Join(field.PrimaryKeys, ", "), strings.Join(tuples, ", "))
+		n, err := exec(ctx, d, conn, query, args...)
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
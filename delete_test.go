@@ -0,0 +1,71 @@
+package korm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelete(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(EmbedModel{}))
+
+	models := []*EmbedModel{
+		{Id: -1, CreateAt: time.Now(), IdentityCard: "card-delete-1"},
+		{Id: -2, CreateAt: time.Now(), IdentityCard: "card-delete-2"},
+		{Id: -3, CreateAt: time.Now(), IdentityCard: "card-delete-3"},
+	}
+	_, err = db.Delete(models)
+	require.NoError(t, err)
+	require.NoError(t, db.Insert(models))
+
+	affected, err := db.Delete(models[0])
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+
+	affected, err = db.Delete(models)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), affected)
+
+	affected, err = db.Delete([]*EmbedModel{})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), affected)
+
+	require.NoError(t, db.RegisterModels(Model{}))
+	_, err = db.Delete(&Model{Id: 1})
+	require.EqualError(t, err, "table model has no primary key")
+}
+
+func TestDeleteCompositeKey(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(CompositeModel{}))
+
+	models := []*CompositeModel{
+		{TenantId: 1, UserId: 1, Name: "a"},
+		{TenantId: 1, UserId: 2, Name: "b"},
+		{TenantId: 2, UserId: 1, Name: "c"},
+	}
+	_, err = db.Exec("DELETE FROM composite_model")
+	require.NoError(t, err)
+	require.NoError(t, db.Insert(models))
+
+	var model CompositeModel
+	require.NoError(t, db.FindByPK(&model, int64(1), int64(2)))
+	require.Equal(t, "b", model.Name)
+
+	model.Name = "updated"
+	affected, err := db.Update(&model)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+
+	affected, err = db.Delete(models[:2])
+	require.NoError(t, err)
+	require.Equal(t, int64(2), affected)
+}
@@ -1,9 +1,25 @@
 package korm
 
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
 type Field struct {
-	TableName string
-	Columns   []string
-	ColumnMap map[string]string
+	TableName   string
+	Columns     []string
+	ColumnMap   map[string]string
+	PrimaryKeys []string
+	// UniqueKeys holds the column groups of the table's unique constraints.
+	UniqueKeys [][]string
+	// Generated lists the columns filled by the database, which are left out
+	// of inserts and read back by InsertReturning.
+	Generated []string
+	// Identity is the GENERATED BY DEFAULT AS IDENTITY column, if any. Inserts
+	// leave it out for rows where it holds the zero value.
+	Identity string
 }
 
 func newField(tableName string) *Field {
@@ -19,3 +35,130 @@ func (f *Field) addColumns(column []string) {
 		f.ColumnMap[c] = c
 	}
 }
+
+func (f *Field) addColumn(column columnDef) {
+	f.addColumns([]string{column.name})
+	if column.pk {
+		f.PrimaryKeys = append(f.PrimaryKeys, column.name)
+	}
+	if column.uk {
+		f.UniqueKeys = append(f.UniqueKeys, []string{column.name})
+	}
+	if column.generated {
+		f.Generated = append(f.Generated, column.name)
+	}
+	if column.identity {
+		f.Identity = column.name
+	}
+}
+
+// insertColumns returns the columns written by Insert, leaving out the ones
+// generated by the database.
+func (f *Field) insertColumns() []string {
+	if len(f.Generated) == 0 {
+		return f.Columns
+	}
+	columns := make([]string, 0, len(f.Columns))
+	for _, column := range f.Columns {
+		if !slices.Contains(f.Generated, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// lookupField returns the registered Field of struct type t.
+func lookupField(d Driver, t reflect.Type) (*Field, error) {
+	field, ok := d.GetTableCache(d.GetDBPattern().TableName(t.Name()))
+	if !ok {
+		return nil, fmt.Errorf("table %s not registered", t.Name())
+	}
+	return field, nil
+}
+
+// pkCondition returns "pk1 = $n AND pk2 = $n+1 ..." with placeholders
+// numbered from start.
+func (f *Field) pkCondition(start int) (string, error) {
+	if len(f.PrimaryKeys) == 0 {
+		return "", fmt.Errorf("table %s has no primary key", f.TableName)
+	}
+	conds := make([]string, len(f.PrimaryKeys))
+	for i, pk := range f.PrimaryKeys {
+		conds[i] = fmt.Sprintf("%s = $%d", pk, start+i)
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+func (f *Field) selectByPKSql() (string, error) {
+	cond, err := f.pkCondition(1)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(f.Columns, ", "), f.TableName, cond), nil
+}
+
+func (f *Field) isPrimaryKey(column string) bool {
+	return slices.Contains(f.PrimaryKeys, column)
+}
+
+// updatableColumns returns the columns written by Update, leaving out the
+// primary key and the columns generated by the database.
+func (f *Field) updatableColumns() []string {
+	columns := make([]string, 0, len(f.Columns))
+	for _, column := range f.Columns {
+		if !f.isPrimaryKey(column) && !slices.Contains(f.Generated, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// insertGroup is a set of rows inserted with the same column list.
+type insertGroup struct {
+	columns []string
+	rows    [][]any
+}
+
+// insertGroups projects rows, whose values follow f.Columns, onto the insert
+// columns. Rows whose identity column holds the zero value are put in a
+// separate group that leaves the identity column out.
+func (f *Field) insertGroups(rows [][]any) []insertGroup {
+	columns := f.insertColumns()
+	all := insertGroup{columns: columns}
+	if len(f.Identity) == 0 {
+		for _, row := range rows {
+			all.rows = append(all.rows, f.columnValues(row, columns))
+		}
+		return []insertGroup{all}
+	}
+
+	withoutIdentity := insertGroup{columns: slices.DeleteFunc(slices.Clone(columns), func(column string) bool {
+		return column == f.Identity
+	})}
+	idx := slices.Index(f.Columns, f.Identity)
+	for _, row := range rows {
+		if row[idx] == nil || reflect.ValueOf(row[idx]).IsZero() {
+			withoutIdentity.rows = append(withoutIdentity.rows, f.columnValues(row, withoutIdentity.columns))
+		} else {
+			all.rows = append(all.rows, f.columnValues(row, all.columns))
+		}
+	}
+
+	groups := make([]insertGroup, 0, 2)
+	for _, group := range []insertGroup{all, withoutIdentity} {
+		if len(group.rows) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// columnValues picks the values of columns out of row, whose values follow
+// f.Columns.
+func (f *Field) columnValues(row []any, columns []string) []any {
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = row[slices.Index(f.Columns, column)]
+	}
+	return values
+}
@@ -4,19 +4,36 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 )
 
+func (s *DB) Insert(data any) error {
+	return s.InsertCtx(context.Background(), data)
+}
+
+func (s *DB) InsertCtx(ctx context.Context, data any) error {
+	return insert(ctx, s, s.conn, data)
+}
+
 func (tx DBTx) Insert(data any) error {
+	return tx.InsertCtx(context.Background(), data)
+}
+
+func (tx DBTx) InsertCtx(ctx context.Context, data any) error {
+	return insert(ctx, tx.Driver, tx.Tx, data)
+}
+
+func insert(ctx context.Context, d Driver, conn dbConn, data any) error {
 	v := reflect.ValueOf(data)
 
 	var rows [][]interface{}
 	var field *Field
-	var ok bool
 	var err error
 	if v.Kind() == reflect.Slice {
-		field, rows, err = tx.buildInsertRows(v)
+		field, rows, err = buildInsertRows(d, v)
 		if err != nil {
 			return err
 		}
@@ -25,12 +42,11 @@ func (tx DBTx) Insert(data any) error {
 		}
 	} else if v.Kind() == reflect.Ptr {
 		t := v.Type().Elem()
-		name := tx.GetDBPattern().TableName(t.Name())
-		if field, ok = tx.GetTableCache(name); !ok {
-			return fmt.Errorf("table %s not registered", t.Name())
+		if field, err = lookupField(d, t); err != nil {
+			return err
 		}
 		rows = make([][]interface{}, 1)
-		row, err := tx.buildInsertRow(field, t, v)
+		row, err := buildInsertRow(d, field, t, v)
 		if err != nil {
 			return err
 		} else {
@@ -40,11 +56,108 @@ func (tx DBTx) Insert(data any) error {
 		return fmt.Errorf("data must be a slice or pointer")
 	}
 
-	_, err = tx.CopyFrom(context.Background(), pgx.Identifier{field.TableName}, field.Columns, pgx.CopyFromRows(rows))
+	for _, group := range field.insertGroups(rows) {
+		if _, err = copyRows(ctx, d, conn, field.TableName, group.columns, group.rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DB) InsertReturning(data any) error {
+	return s.InsertReturningCtx(context.Background(), data)
+}
+
+func (s *DB) InsertReturningCtx(ctx context.Context, data any) error {
+	return insertReturning(ctx, s, s.conn, data)
+}
+
+func (tx DBTx) InsertReturning(data any) error {
+	return tx.InsertReturningCtx(context.Background(), data)
+}
+
+func (tx DBTx) InsertReturningCtx(ctx context.Context, data any) error {
+	return insertReturning(ctx, tx.Driver, tx.Tx, data)
+}
+
+// insertReturning inserts data, a struct pointer or a slice of them, with a
+// batch of INSERT ... RETURNING statements and writes the generated and
+// identity columns back into each struct.
+func insertReturning(ctx context.Context, d Driver, conn dbConn, data any) error {
+	t, elems, err := modelValues(data)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return nil
+	}
+	field, err := lookupField(d, t)
+	if err != nil {
+		return err
+	}
+
+	returning := field.Generated
+	if len(field.Identity) > 0 && !slices.Contains(returning, field.Identity) {
+		returning = append(slices.Clone(returning), field.Identity)
+	}
+
+	var query string
+	batch := &pgx.Batch{}
+	for _, elem := range elems {
+		row, err := buildInsertRow(d, field, t, elem)
+		if err != nil {
+			return err
+		}
+		group := field.insertGroups([][]any{row})[0]
+		query = insertReturningSql(field.TableName, group.columns, returning)
+		batch.Queue(query, group.rows[0]...)
+	}
+
+	event := &QueryEvent{Op: OpExec, Table: field.TableName, SQL: query}
+	_, err = traceQuery(ctx, d.GetTracer(), event, func(ctx context.Context) (int64, error) {
+		results := conn.SendBatch(ctx, batch)
+		defer results.Close()
+		for _, elem := range elems {
+			if len(returning) == 0 {
+				if _, err := results.Exec(); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			if err := results.QueryRow().Scan(columnAddrs(d, elem, returning)...); err != nil {
+				return 0, err
+			}
+		}
+		return int64(len(elems)), results.Close()
+	})
 	return err
 }
 
-func (tx DBTx) buildInsertRows(v reflect.Value) (*Field, [][]interface{}, error) {
+func insertReturningSql(tableName string, columns []string, returning []string) string {
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		tableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if len(returning) > 0 {
+		query += " RETURNING " + strings.Join(returning, ", ")
+	}
+	return query
+}
+
+func copyRows(ctx context.Context, d Driver, conn dbConn, tableName string, columns []string, rows [][]any) (int64, error) {
+	event := &QueryEvent{
+		Op:    OpCopy,
+		Table: tableName,
+		SQL:   fmt.Sprintf("COPY %s (%s) FROM STDIN", tableName, strings.Join(columns, ", ")),
+	}
+	return traceQuery(ctx, d.GetTracer(), event, func(ctx context.Context) (int64, error) {
+		return conn.CopyFrom(ctx, pgx.Identifier{tableName}, columns, pgx.CopyFromRows(rows))
+	})
+}
+
+func buildInsertRows(d Driver, v reflect.Value) (*Field, [][]interface{}, error) {
 	if v.Len() == 0 {
 		return nil, nil, nil
 	}
@@ -54,14 +167,14 @@ func (tx DBTx) buildInsertRows(v reflect.Value) (*Field, [][]interface{}, error)
 	}
 	t = t.Elem()
 
-	field, ok := tx.GetTableCache(tx.GetDBPattern().TableName(t.Name()))
-	if !ok {
-		return nil, nil, fmt.Errorf("table %s not registered", t.Name())
+	field, err := lookupField(d, t)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	rows := make([][]interface{}, v.Len())
 	for i := 0; i < v.Len(); i++ {
-		row, err := tx.buildInsertRow(field, t, v.Index(i))
+		row, err := buildInsertRow(d, field, t, v.Index(i))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -70,23 +183,49 @@ func (tx DBTx) buildInsertRows(v reflect.Value) (*Field, [][]interface{}, error)
 	return field, rows, nil
 }
 
-func (tx DBTx) buildInsertRow(field *Field, t reflect.Type, v reflect.Value) ([]interface{}, error) {
-	v = v.Elem()
-	row := make([]interface{}, 0, len(field.Columns))
-	for j := 0; j < v.NumField(); j++ {
-		f := v.Field(j)
-		tf := t.Field(j)
-		if isFieldEmbed(tf) {
-			for k := 0; k < f.NumField(); k++ {
-				row = append(row, f.Field(k).Interface())
-			}
+// buildInsertRow returns the values of v, a struct pointer, in the order of
+// field.Columns. Fields are matched to columns by name, so fields skipped
+// with db:"-" never shift the values of the columns after them.
+func buildInsertRow(d Driver, field *Field, t reflect.Type, v reflect.Value) ([]interface{}, error) {
+	fields := make(map[string]any, len(field.Columns))
+	collectColumnAddrs(d, v.Elem(), fields)
+	row := make([]interface{}, len(field.Columns))
+	for i, column := range field.Columns {
+		addr, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("column %s not found in %s", column, t.Name())
+		}
+		row[i] = reflect.ValueOf(addr).Elem().Interface()
+	}
+	return row, nil
+}
+
+// columnAddrs returns pointers to the fields of v, a struct pointer, that map
+// to columns.
+func columnAddrs(d Driver, v reflect.Value, columns []string) []any {
+	fields := make(map[string]any)
+	collectColumnAddrs(d, v.Elem(), fields)
+	addrs := make([]any, len(columns))
+	for i, column := range columns {
+		addrs[i] = fields[column]
+	}
+	return addrs
+}
+
+// collectColumnAddrs maps the columns of v, a struct, to pointers to its
+// fields. Unexported fields and fields tagged db:"-" are skipped, as they are
+// when the model is registered.
+func collectColumnAddrs(d Driver, v reflect.Value, fields map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _ := parseTag(t.Field(i).Tag.Get("db"))
+		if _, ok := tag["-"]; ok || !t.Field(i).IsExported() {
 			continue
 		}
-		vName := tx.GetDBPattern().ColumnName(v.Type().Field(j).Name)
-		if _, ok := field.ColumnMap[vName]; !ok {
+		if isFieldEmbed(t.Field(i)) {
+			collectColumnAddrs(d, v.Field(i), fields)
 			continue
 		}
-		row = append(row, f.Interface())
+		fields[d.GetDBPattern().ColumnName(t.Field(i).Name)] = v.Field(i).Addr().Interface()
 	}
-	return row, nil
 }
@@ -71,3 +71,54 @@ func TestInsertMany(t *testing.T) {
 		return tx.Insert(students)
 	}))
 }
+
+func TestInsertReturning(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(GeneratedModel{}))
+
+	models := []*GeneratedModel{{Id: 1, Name: "name1"}, {Id: 2, Name: "name2"}}
+	assert.NoError(t, WithTx(db, func(tx Transaction) error {
+		if _, err := tx.Exec("DELETE FROM generated_model"); err != nil {
+			return err
+		}
+		return tx.InsertReturning(models)
+	}))
+	for _, model := range models {
+		assert.False(t, model.CreateAt.IsZero())
+	}
+}
+
+func TestField_insertGroups(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(IdentityModel{})
+	require.NoError(t, err)
+	field, _ := db.GetTableCache("identity_model")
+	require.Equal(t, "id", field.Identity)
+
+	groups := field.insertGroups([][]any{{int64(0), "a"}, {int64(5), "b"}, {int64(0), "c"}})
+	require.Equal(t, []insertGroup{
+		{columns: []string{"id", "name"}, rows: [][]any{{int64(5), "b"}}},
+		{columns: []string{"name"}, rows: [][]any{{"a"}, {"c"}}},
+	}, groups)
+}
+
+func TestInsertIdentity(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(IdentityModel{}))
+
+	models := []*IdentityModel{{Name: "name1"}, {Name: "name2"}}
+	assert.NoError(t, WithTx(db, func(tx Transaction) error {
+		if err := tx.Insert(models); err != nil {
+			return err
+		}
+		return tx.InsertReturning(models)
+	}))
+	assert.NotZero(t, models[0].Id)
+	assert.NotEqual(t, models[0].Id, models[1].Id)
+}
@@ -2,35 +2,146 @@ package korm
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// dbConn is the subset of *pgx.Conn, *pgxpool.Pool and pgx.Tx used to run
+// statements. Begin on a pgx.Tx starts a savepoint.
+type dbConn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// rootConn is a dbConn that can also start transactions with options.
+type rootConn interface {
+	dbConn
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
 type DB struct {
-	*pgx.Conn
+	conn  rootConn
+	close func() error
 	DBPattern
+	// Tracer, when set, is called around every statement run by the DB and
+	// its transactions.
+	Tracer     Tracer
+	cacheLock  sync.RWMutex
 	tableCache map[string]*Field
 }
 
+// PoolOptions tunes the pool created by NewPool. Zero values keep the
+// pgxpool defaults or the values given in the connection string.
+type PoolOptions struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
 func NewDB(connStr string) (*DB, error) {
 	conn, err := pgx.Connect(context.Background(), connStr)
 	if err != nil {
 		return nil, err
 	}
 
-	return initDB(conn), nil
+	db := initDB(conn)
+	db.close = func() error { return conn.Close(context.Background()) }
+	return db, nil
 }
 
-func initDB(conn *pgx.Conn) *DB {
-	return &DB{Conn: conn, DBPattern: defaultPattern, tableCache: make(map[string]*Field)}
+// NewPool returns a DB backed by a pgxpool.Pool, which is safe for
+// concurrent use. Each transaction acquires its own connection from the pool.
+func NewPool(connStr string, opts *PoolOptions) (*DB, error) {
+	config, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, err
+	}
+	opts.apply(config)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+
+	db := initDB(pool)
+	db.close = func() error {
+		pool.Close()
+		return nil
+	}
+	return db, nil
+}
+
+func (o *PoolOptions) apply(config *pgxpool.Config) {
+	if o == nil {
+		return
+	}
+	if o.MaxConns > 0 {
+		config.MaxConns = o.MaxConns
+	}
+	if o.MinConns > 0 {
+		config.MinConns = o.MinConns
+	}
+	if o.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = o.MaxConnLifetime
+	}
+	if o.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = o.MaxConnIdleTime
+	}
+	if o.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = o.HealthCheckPeriod
+	}
+}
+
+func initDB(conn rootConn) *DB {
+	return &DB{conn: conn, DBPattern: defaultPattern, tableCache: make(map[string]*Field)}
+}
+
+// Conn returns the connection of a DB created by NewDB, or nil when the DB
+// is backed by a pool.
+func (s *DB) Conn() *pgx.Conn {
+	conn, _ := s.conn.(*pgx.Conn)
+	return conn
+}
+
+// Pool returns the pool of a DB created by NewPool, or nil when the DB is
+// backed by a single connection.
+func (s *DB) Pool() *pgxpool.Pool {
+	pool, _ := s.conn.(*pgxpool.Pool)
+	return pool
 }
 
 func (s *DB) Close() error {
-	return s.Conn.Close(context.Background())
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
 }
 
 func (s *DB) Begin() (Transaction, error) {
-	tx, err := s.Conn.Begin(context.Background())
+	return s.BeginCtx(context.Background())
+}
+
+func (s *DB) BeginCtx(ctx context.Context) (Transaction, error) {
+	tx, err := s.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DBTx{Tx: tx, Driver: s}, nil
+}
+
+// BeginTx starts a top-level transaction with the given isolation level,
+// access mode and deferrable mode.
+func (s *DB) BeginTx(ctx context.Context, opts TxOptions) (Transaction, error) {
+	tx, err := s.conn.BeginTx(ctx, opts.pgxOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -41,17 +152,56 @@ func (s *DB) GetDBPattern() DBPattern {
 	return s.DBPattern
 }
 
+func (s *DB) GetTracer() Tracer {
+	return s.Tracer
+}
+
 func (s *DB) GetTableCache(name string) (*Field, bool) {
+	s.cacheLock.RLock()
+	defer s.cacheLock.RUnlock()
 	f, ok := s.tableCache[name]
 	return f, ok
 }
 
+func (s *DB) setTableCache(field *Field) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.tableCache[field.TableName] = field
+}
+
 func (s *DB) Exec(sql string, args ...any) (int64, error) {
-	pgTag, err := s.Conn.Exec(context.Background(), sql, args...)
-	if err != nil {
-		return 0, err
+	return s.ExecCtx(context.Background(), sql, args...)
+}
+
+func (s *DB) ExecCtx(ctx context.Context, sql string, args ...any) (int64, error) {
+	return exec(ctx, s, s.conn, sql, args...)
+}
+
+type IsolationLevel string
+
+const (
+	ReadCommitted  IsolationLevel = "read committed"
+	RepeatableRead IsolationLevel = "repeatable read"
+	Serializable   IsolationLevel = "serializable"
+)
+
+// TxOptions configures a transaction started by BeginTx. The zero value uses
+// the server defaults.
+type TxOptions struct {
+	IsoLevel   IsolationLevel
+	ReadOnly   bool
+	Deferrable bool
+}
+
+func (o TxOptions) pgxOptions() pgx.TxOptions {
+	opts := pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(o.IsoLevel)}
+	if o.ReadOnly {
+		opts.AccessMode = pgx.ReadOnly
+	}
+	if o.Deferrable {
+		opts.DeferrableMode = pgx.Deferrable
 	}
-	return pgTag.RowsAffected(), nil
+	return opts
 }
 
 type DBTx struct {
@@ -59,18 +209,51 @@ type DBTx struct {
 	Driver
 }
 
+func (tx DBTx) Begin() (Transaction, error) {
+	return tx.BeginCtx(context.Background())
+}
+
+// BeginCtx starts a nested transaction using a SAVEPOINT. Rolling it back
+// only undoes the work done since the nested Begin.
+func (tx DBTx) BeginCtx(ctx context.Context) (Transaction, error) {
+	nested, err := tx.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DBTx{Tx: nested, Driver: tx.Driver}, nil
+}
+
 func (tx DBTx) Commit() error {
-	return tx.Tx.Commit(context.Background())
+	return tx.CommitCtx(context.Background())
+}
+
+func (tx DBTx) CommitCtx(ctx context.Context) error {
+	return tx.Tx.Commit(ctx)
 }
 
 func (tx DBTx) Rollback() error {
-	return tx.Tx.Rollback(context.Background())
+	return tx.RollbackCtx(context.Background())
+}
+
+func (tx DBTx) RollbackCtx(ctx context.Context) error {
+	return tx.Tx.Rollback(ctx)
 }
 
 func (tx DBTx) Exec(query string, args ...any) (int64, error) {
-	result, err := tx.Tx.Exec(context.Background(), query, args...)
-	if err != nil {
-		return 0, err
-	}
-	return result.RowsAffected(), nil
+	return tx.ExecCtx(context.Background(), query, args...)
+}
+
+func (tx DBTx) ExecCtx(ctx context.Context, query string, args ...any) (int64, error) {
+	return exec(ctx, tx.Driver, tx.Tx, query, args...)
+}
+
+func exec(ctx context.Context, d Driver, conn dbConn, sql string, args ...any) (int64, error) {
+	event := &QueryEvent{Op: OpExec, SQL: sql, Args: args}
+	return traceQuery(ctx, d.GetTracer(), event, func(ctx context.Context) (int64, error) {
+		pgTag, err := conn.Exec(ctx, sql, args...)
+		if err != nil {
+			return 0, err
+		}
+		return pgTag.RowsAffected(), nil
+	})
 }
@@ -1,30 +1,145 @@
 package korm
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
 type Driver interface {
 	Close() error
-	Begin() (Transaction, error)
+	Beginner
+	BeginTx(ctx context.Context, opts TxOptions) (Transaction, error)
 	GetDBPattern() DBPattern
+	GetTracer() Tracer
 	GetTableCache(name string) (*Field, bool)
-	Exec(sql string, args ...any) (int64, error)
+	Querier
 }
 
-type Transaction interface {
+// Querier is implemented by both DB and Transaction, so helpers can run
+// the same way inside or outside a transaction.
+type Querier interface {
 	Insert(data any) error
+	InsertCtx(ctx context.Context, data any) error
+	InsertReturning(data any) error
+	InsertReturningCtx(ctx context.Context, data any) error
 	Select(target any, query string, args ...any) error
+	SelectCtx(ctx context.Context, target any, query string, args ...any) error
+	Get(dest any, query string, args ...any) error
+	GetCtx(ctx context.Context, dest any, query string, args ...any) error
+	First(dest any, query string, args ...any) error
+	FirstCtx(ctx context.Context, dest any, query string, args ...any) error
+	FindByPK(dest any, keys ...any) error
+	FindByPKCtx(ctx context.Context, dest any, keys ...any) error
+	Update(model any) (int64, error)
+	UpdateCtx(ctx context.Context, model any) (int64, error)
+	UpdateColumns(model any, columns ...string) (int64, error)
+	UpdateColumnsCtx(ctx context.Context, model any, columns ...string) (int64, error)
+	UpdateFields(model any, fields map[string]any) (int64, error)
+	UpdateFieldsCtx(ctx context.Context, model any, fields map[string]any) (int64, error)
+	Upsert(data any, conflictColumns []string, updateColumns []string) (int64, error)
+	UpsertCtx(ctx context.Context, data any, conflictColumns []string, updateColumns []string) (int64, error)
+	Delete(model any) (int64, error)
+	DeleteCtx(ctx context.Context, model any) (int64, error)
 	Exec(query string, args ...any) (int64, error)
+	ExecCtx(ctx context.Context, query string, args ...any) (int64, error)
+}
+
+// Beginner starts transactions. A Driver begins a top-level transaction and a
+// Transaction begins a nested one backed by a SAVEPOINT.
+type Beginner interface {
+	Begin() (Transaction, error)
+	BeginCtx(ctx context.Context) (Transaction, error)
+}
+
+type Transaction interface {
+	Querier
+	Beginner
 	Rollback() error
+	RollbackCtx(ctx context.Context) error
 	Commit() error
+	CommitCtx(ctx context.Context) error
+}
+
+func WithTx(d Beginner, f func(tx Transaction) error) error {
+	return WithTxCtx(context.Background(), d, f)
+}
+
+func WithTxCtx(ctx context.Context, d Beginner, f func(tx Transaction) error) error {
+	tx, err := d.BeginCtx(ctx)
+	if err != nil {
+		return err
+	}
+	return runTx(ctx, tx, false, f)
+}
+
+// WithTxOptions is like WithTxCtx but starts the transaction with opts,
+// e.g. TxOptions{IsoLevel: Serializable, ReadOnly: true, Deferrable: true}.
+func WithTxOptions(ctx context.Context, d Driver, opts TxOptions, f func(tx Transaction) error) error {
+	tx, err := d.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return runTx(ctx, tx, false, f)
 }
 
-func WithTx(d Driver, f func(tx Transaction) error) error {
-	tx, err := d.Begin()
+// WithTxRecover is like WithTxCtx but returns a panic in f as a *PanicError
+// after rolling back, instead of re-panicking.
+func WithTxRecover(ctx context.Context, d Beginner, f func(tx Transaction) error) error {
+	tx, err := d.BeginCtx(ctx)
 	if err != nil {
 		return err
 	}
+	return runTx(ctx, tx, true, f)
+}
+
+// PanicError is returned by WithTxRecover when f panics. The other
+// transaction helpers re-panic with it when the rollback after a panic
+// fails, so that the rollback error is not lost.
+type PanicError struct {
+	Value       any
+	Stack       []byte
+	RollbackErr error
+}
+
+func (e *PanicError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("transaction panicked: %v, rollback failed: %v", e.Value, e.RollbackErr)
+	}
+	return fmt.Sprintf("transaction panicked: %v", e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return e.RollbackErr
+}
+
+// runTx commits tx when f succeeds and rolls it back when f returns an error
+// or panics. A panic is re-raised after the rollback unless recoverPanic is
+// set, in which case it is returned as a *PanicError. When the rollback
+// fails, the panic is re-raised as a *PanicError carrying the rollback error.
+func runTx(ctx context.Context, tx Transaction, recoverPanic bool, f func(tx Transaction) error) (err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		rbErr := tx.RollbackCtx(ctx)
+		if !recoverPanic && rbErr == nil {
+			panic(p)
+		}
+		panicErr := &PanicError{Value: p, Stack: debug.Stack(), RollbackErr: rbErr}
+		if !recoverPanic {
+			panic(panicErr)
+		}
+		err = panicErr
+	}()
 
 	if err = f(tx); err != nil {
-		tx.Rollback()
+		if rbErr := tx.RollbackCtx(ctx); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback failed: %w", rbErr))
+		}
 		return err
 	}
-	return tx.Commit()
+	return tx.CommitCtx(ctx)
 }
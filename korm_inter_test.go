@@ -0,0 +1,76 @@
+package korm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTx struct {
+	Transaction
+	rollbackErr error
+	rollbacked  bool
+	committed   bool
+}
+
+func (tx *fakeTx) RollbackCtx(context.Context) error {
+	tx.rollbacked = true
+	return tx.rollbackErr
+}
+
+func (tx *fakeTx) CommitCtx(context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func TestRunTx(t *testing.T) {
+	ctx := context.Background()
+	fnErr := errors.New("fn failed")
+	rbErr := errors.New("conn closed")
+
+	tx := &fakeTx{}
+	require.NoError(t, runTx(ctx, tx, false, func(Transaction) error { return nil }))
+	assert.True(t, tx.committed)
+	assert.False(t, tx.rollbacked)
+
+	tx = &fakeTx{rollbackErr: rbErr}
+	err := runTx(ctx, tx, false, func(Transaction) error { return fnErr })
+	assert.True(t, tx.rollbacked)
+	assert.ErrorIs(t, err, fnErr)
+	assert.ErrorIs(t, err, rbErr)
+
+	tx = &fakeTx{}
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = runTx(ctx, tx, false, func(Transaction) error { panic("boom") })
+	})
+	assert.True(t, tx.rollbacked)
+	assert.False(t, tx.committed)
+
+	tx = &fakeTx{}
+	err = runTx(ctx, tx, true, func(Transaction) error { panic("boom") })
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.True(t, tx.rollbacked)
+
+	// A failed rollback is reported with the panic it followed.
+	tx = &fakeTx{rollbackErr: rbErr}
+	func() {
+		defer func() {
+			panicErr, ok := recover().(*PanicError)
+			require.True(t, ok)
+			assert.Equal(t, "boom", panicErr.Value)
+			assert.ErrorIs(t, panicErr, rbErr)
+		}()
+		_ = runTx(ctx, tx, false, func(Transaction) error { panic("boom") })
+	}()
+
+	tx = &fakeTx{rollbackErr: rbErr}
+	err = runTx(ctx, tx, true, func(Transaction) error { panic("boom") })
+	require.ErrorAs(t, err, &panicErr)
+	assert.ErrorIs(t, err, rbErr)
+	assert.EqualError(t, err, "transaction panicked: boom, rollback failed: conn closed")
+}
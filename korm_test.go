@@ -1,9 +1,15 @@
 package korm
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,3 +34,97 @@ func TestNewDB(t *testing.T) {
 	require.NoError(t, err)
 	defer db.Close()
 }
+
+func TestNewPool(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewPool(connStr, &PoolOptions{MaxConns: 4})
+	require.NoError(t, err)
+	defer db.Close()
+	require.NotNil(t, db.Pool())
+	require.Nil(t, db.Conn())
+	require.NoError(t, db.Pool().Ping(context.Background()))
+	require.NoError(t, db.RegisterModels(Student{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, WithTx(db, func(tx Transaction) error {
+				var result []*Student
+				return tx.Select(&result, "SELECT * FROM student LIMIT 1")
+			}))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithTxCtxCanceled(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = WithTxCtx(ctx, db, func(tx Transaction) error {
+		_, err := tx.ExecCtx(ctx, "SELECT 1")
+		return err
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNestedTx(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.RegisterModels(Student{}))
+
+	var result []*Student
+	require.NoError(t, WithTx(db, func(tx Transaction) error {
+		if _, err := tx.Exec("DELETE FROM student WHERE id IN (-10, -11)"); err != nil {
+			return err
+		}
+		if err := tx.Insert(&Student{Id: -10, CreateAt: time.Now()}); err != nil {
+			return err
+		}
+		nestedErr := WithTx(tx, func(tx Transaction) error {
+			if err := tx.Insert(&Student{Id: -11, CreateAt: time.Now()}); err != nil {
+				return err
+			}
+			return fmt.Errorf("rollback nested")
+		})
+		assert.EqualError(t, nestedErr, "rollback nested")
+		return tx.Select(&result, "SELECT * FROM student WHERE id IN (-10, -11)")
+	}))
+	require.Len(t, result, 1)
+	assert.Equal(t, -10, result[0].Id)
+}
+
+func TestTxOptions_pgxOptions(t *testing.T) {
+	assert.Equal(t, pgx.TxOptions{}, TxOptions{}.pgxOptions())
+	assert.Equal(t, pgx.TxOptions{
+		IsoLevel:       pgx.Serializable,
+		AccessMode:     pgx.ReadOnly,
+		DeferrableMode: pgx.Deferrable,
+	}, TxOptions{IsoLevel: Serializable, ReadOnly: true, Deferrable: true}.pgxOptions())
+}
+
+func TestWithTxOptions(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	opts := TxOptions{IsoLevel: Serializable, ReadOnly: true, Deferrable: true}
+	err = WithTxOptions(context.Background(), db, opts, func(tx Transaction) error {
+		_, err := tx.Exec("CREATE TEMP TABLE read_only_check (id INT)")
+		return err
+	})
+	assert.ErrorContains(t, err, "read-only transaction")
+}
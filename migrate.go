@@ -0,0 +1,205 @@
+package korm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MigrateOptions controls which destructive changes AutoMigrate may make to
+// an existing table. Missing columns and indexes are always added.
+type MigrateOptions struct {
+	// AllowTypeChange lets AutoMigrate alter a column whose type differs
+	// from the model. Otherwise such a column is reported as an error.
+	AllowTypeChange bool
+	// AllowDropColumn lets AutoMigrate drop columns that are no longer in
+	// the model. Otherwise they are left in place.
+	AllowDropColumn bool
+}
+
+// liveColumnsSql lists the columns of a table, in order, with their types as
+// format_type prints them. It returns no rows when the table does not exist.
+const liveColumnsSql = `SELECT a.attname, format_type(a.atttypid, a.atttypmod)
+FROM pg_attribute a
+WHERE a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`
+
+func (s *DB) AutoMigrate(opts MigrateOptions, models ...any) error {
+	return s.AutoMigrateCtx(context.Background(), opts, models...)
+}
+
+// AutoMigrateCtx creates the tables of models that do not exist yet and
+// brings existing ones in line with the model: missing columns and indexes
+// are added, and column types are changed or stale columns dropped when opts
+// allows it.
+func (s *DB) AutoMigrateCtx(ctx context.Context, opts MigrateOptions, models ...any) error {
+	schemas, err := s.buildTableSchemas(models)
+	if err != nil {
+		return err
+	}
+	foreignKeySql := make([][]string, len(schemas))
+	for i, schema := range schemas {
+		tableName := schema.field.TableName
+		live, err := s.liveColumns(ctx, tableName)
+		if err != nil {
+			return fmt.Errorf("read table %s failed: %w", tableName, err)
+		}
+		sqlList, err := schema.migrateSql(live, opts)
+		if err != nil {
+			return err
+		}
+		if err := s.execDDL(ctx, tableName, sqlList); err != nil {
+			return fmt.Errorf("migrate table %s failed: %w", schema.modelName, err)
+		}
+		foreignKeySql[i] = schema.foreignKeySql(live)
+		s.setTableCache(schema.field)
+	}
+	for i, schema := range schemas {
+		if err := s.execDDL(ctx, schema.field.TableName, foreignKeySql[i]); err != nil {
+			return fmt.Errorf("migrate table %s failed: %w", schema.modelName, err)
+		}
+	}
+	return nil
+}
+
+// liveColumns returns the columns of tableName in the database mapped to
+// their types, or an empty map when the table does not exist.
+func (s *DB) liveColumns(ctx context.Context, tableName string) (map[string]string, error) {
+	columns := make(map[string]string)
+	event := &QueryEvent{Op: OpSelect, Table: tableName, SQL: liveColumnsSql, Args: []any{tableName}}
+	_, err := traceQuery(ctx, s.Tracer, event, func(ctx context.Context) (int64, error) {
+		rows, err := s.conn.Query(ctx, liveColumnsSql, tableName)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, dataType string
+			if err := rows.Scan(&name, &dataType); err != nil {
+				return 0, err
+			}
+			columns[name] = dataType
+		}
+		rows.Close()
+		return rows.CommandTag().RowsAffected(), rows.Err()
+	})
+	return columns, err
+}
+
+// migrateSql returns the statements that turn a table with the live columns
+// into ts. A table without live columns is created from scratch.
+func (ts *tableSchema) migrateSql(live map[string]string, opts MigrateOptions) ([]string, error) {
+	if len(live) == 0 {
+		return ts.sqlList(), nil
+	}
+
+	tableName := ts.field.TableName
+	inlinePK := len(ts.field.PrimaryKeys) == 1
+	sqlList := make([]string, 0, len(ts.createIdxSql))
+	for _, column := range ts.columns {
+		liveType, ok := live[column.name]
+		if !ok {
+			sqlList = append(sqlList, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", tableName, column.definition(inlinePK)))
+			continue
+		}
+		if sameType(column.dbType, liveType) {
+			continue
+		}
+		if !opts.AllowTypeChange {
+			return nil, column.typeMismatch(tableName, liveType)
+		}
+		sqlList = append(sqlList, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;",
+			tableName, column.name, column.dbType, column.name, column.dbType))
+	}
+
+	if opts.AllowDropColumn {
+		stale := make([]string, 0, len(live))
+		for name := range live {
+			if _, ok := ts.field.ColumnMap[name]; !ok {
+				stale = append(stale, name)
+			}
+		}
+		slices.Sort(stale)
+		for _, name := range stale {
+			sqlList = append(sqlList, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", tableName, name))
+		}
+	}
+
+	return append(sqlList, ts.createIdxSql...), nil
+}
+
+func (s *DB) VerifyModels(models ...any) error {
+	return s.VerifyModelsCtx(context.Background(), models...)
+}
+
+// VerifyModelsCtx checks that the table of every model exists and has its
+// columns with matching types. All mismatches are reported, joined into one
+// error. Extra columns in the database are ignored.
+func (s *DB) VerifyModelsCtx(ctx context.Context, models ...any) error {
+	var errs []error
+	for _, model := range models {
+		schema, err := s.buildTableSchema(model)
+		if err != nil {
+			return err
+		}
+		tableName := schema.field.TableName
+		live, err := s.liveColumns(ctx, tableName)
+		if err != nil {
+			return fmt.Errorf("read table %s failed: %w", tableName, err)
+		}
+		errs = append(errs, schema.verify(live)...)
+	}
+	return errors.Join(errs...)
+}
+
+// verify compares ts with the live columns of its table.
+func (ts *tableSchema) verify(live map[string]string) []error {
+	tableName := ts.field.TableName
+	if len(live) == 0 {
+		return []error{fmt.Errorf("table %s does not exist", tableName)}
+	}
+	var errs []error
+	for _, column := range ts.columns {
+		liveType, ok := live[column.name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("column %s not found in table %s", column.name, tableName))
+		} else if !sameType(column.dbType, liveType) {
+			errs = append(errs, column.typeMismatch(tableName, liveType))
+		}
+	}
+	return errs
+}
+
+func (c columnDef) typeMismatch(tableName string, liveType string) error {
+	return fmt.Errorf("column %s of table %s has type %s, model wants %s", c.name, tableName, liveType, c.dbType)
+}
+
+// formatTypeNames maps the types generated by goTypeToPostgresType to the
+// names format_type reports for them.
+var formatTypeNames = map[string]string{
+	"FLOAT4":    "real",
+	"TIMESTAMP": "timestamp without time zone",
+}
+
+// sameType reports whether liveType, as printed by format_type, is the
+// column type dbType. Type modifiers such as numeric(10,2) are ignored.
+func sameType(dbType, liveType string) bool {
+	base, isArray := strings.CutSuffix(dbType, "[]")
+	name, ok := formatTypeNames[base]
+	if !ok {
+		name = strings.ToLower(base)
+	}
+	if isArray {
+		name += "[]"
+	}
+
+	if start := strings.IndexByte(liveType, '('); start >= 0 {
+		if end := strings.IndexByte(liveType[start:], ')'); end >= 0 {
+			liveType = liveType[:start] + liveType[start+end+1:]
+		}
+	}
+	return name == liveType
+}
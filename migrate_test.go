@@ -0,0 +1,84 @@
+package korm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableSchema_migrateSql(t *testing.T) {
+	db := initDB(nil)
+	schema, err := db.buildTableSchema(IndexModel{})
+	require.NoError(t, err)
+
+	// A missing table is created from scratch.
+	sqlList, err := schema.migrateSql(nil, MigrateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, append([]string{schema.createTableSql}, schema.createIdxSql...), sqlList)
+
+	live := map[string]string{
+		"id":            "bigint",
+		"create_at":     "timestamp without time zone",
+		"name":          "text",
+		"alias":         "character varying(64)",
+		"identity_card": "text",
+		"json_column":   "jsonb",
+		"json_map":      "jsonb",
+		"address":       "cidr",
+		"nickname":      "text",
+	}
+	_, err = schema.migrateSql(live, MigrateOptions{})
+	require.EqualError(t, err, "column alias of table index_model has type character varying(64), model wants TEXT")
+
+	live["alias"] = "text"
+	sqlList, err = schema.migrateSql(live, MigrateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, append([]string{
+		"ALTER TABLE index_model ADD COLUMN age INTEGER;",
+	}, schema.createIdxSql...), sqlList)
+
+	live["age"] = "bigint"
+	sqlList, err = schema.migrateSql(live, MigrateOptions{AllowTypeChange: true, AllowDropColumn: true})
+	require.NoError(t, err)
+	require.Equal(t, append([]string{
+		"ALTER TABLE index_model ALTER COLUMN age TYPE INTEGER USING age::INTEGER;",
+		"ALTER TABLE index_model DROP COLUMN nickname;",
+	}, schema.createIdxSql...), sqlList)
+}
+
+func TestTableSchema_verify(t *testing.T) {
+	db := initDB(nil)
+	schema, err := db.buildTableSchema(IdentityModel{})
+	require.NoError(t, err)
+
+	require.Equal(t, []error{errors.New("table identity_model does not exist")}, schema.verify(nil))
+	require.Empty(t, schema.verify(map[string]string{"id": "bigint", "name": "text", "extra": "text"}))
+	require.Equal(t, []error{
+		errors.New("column id of table identity_model has type integer, model wants BIGINT"),
+		errors.New("column name not found in table identity_model"),
+	}, schema.verify(map[string]string{"id": "integer"}))
+}
+
+func TestSameType(t *testing.T) {
+	datas := []struct {
+		dbType   string
+		liveType string
+		expect   bool
+	}{
+		{"BIGINT", "bigint", true},
+		{"FLOAT4", "real", true},
+		{"TIMESTAMP", "timestamp without time zone", true},
+		{"NUMERIC", "numeric(10,2)", true},
+		{"TEXT[]", "text[]", true},
+		{"INET[]", "inet[]", true},
+		{"TEXT", "character varying(64)", false},
+		{"INTEGER", "bigint", false},
+		{"TEXT[]", "text", false},
+	}
+	for _, data := range datas {
+		t.Run(data.dbType+"/"+data.liveType, func(t *testing.T) {
+			require.Equal(t, data.expect, sameType(data.dbType, data.liveType))
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package korm
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// migrationTable records the versions a Migrator has applied.
+const migrationTable = "schema_migrations"
+
+// migrationLockKey is the advisory lock held while migrating, so that two
+// processes never apply migrations at the same time.
+const migrationLockKey int64 = 0x6b6f726d
+
+// undefinedTableCode is the SQLSTATE returned when a table does not exist.
+const undefinedTableCode = "42P01"
+
+const createMigrationTableSql = `CREATE TABLE IF NOT EXISTS ` + migrationTable + ` (
+version BIGINT PRIMARY KEY,
+name TEXT NOT NULL,
+applied_at TIMESTAMP NOT NULL DEFAULT now()
+);`
+
+// Migration is one versioned schema change. Down may be nil for migrations
+// that cannot be reverted.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, tx Transaction) error
+	Down    func(ctx context.Context, tx Transaction) error
+}
+
+// SQLMigration returns a Migration that runs the up and down scripts. An
+// empty down script makes the migration irreversible.
+func SQLMigration(version int64, name string, up string, down string) Migration {
+	migration := Migration{Version: version, Name: name, Up: execScript(up)}
+	if len(strings.TrimSpace(down)) > 0 {
+		migration.Down = execScript(down)
+	}
+	return migration
+}
+
+func execScript(script string) func(ctx context.Context, tx Transaction) error {
+	return func(ctx context.Context, tx Transaction) error {
+		_, err := tx.ExecCtx(ctx, script)
+		return err
+	}
+}
+
+// MigrationStatus reports whether a migration has been applied. Migrations
+// recorded in the database but no longer registered are reported too.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator applies registered migrations in version order and records them
+// in the schema_migrations table. Up and Down each run in one transaction
+// that holds an advisory lock, so either every step is applied or none is.
+type Migrator struct {
+	d          Driver
+	migrations []Migration
+}
+
+func NewMigrator(d Driver) *Migrator {
+	return &Migrator{d: d}
+}
+
+func (m *Migrator) Register(migrations ...Migration) error {
+	for _, migration := range migrations {
+		if migration.Up == nil {
+			return fmt.Errorf("migration %d has no up step", migration.Version)
+		}
+		if _, ok := m.find(migration.Version); ok {
+			return fmt.Errorf("migration %d already registered", migration.Version)
+		}
+		m.migrations = append(m.migrations, migration)
+	}
+	slices.SortFunc(m.migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return nil
+}
+
+// RegisterFS registers the SQL migrations in dir, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Other files are
+// ignored.
+func (m *Migrator) RegisterFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	type scripts struct {
+		name           string
+		up, down       string
+		hasUp, hasDown bool
+	}
+	found := make(map[int64]*scripts)
+	versions := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fileName := entry.Name()
+		base, up := strings.CutSuffix(fileName, ".up.sql")
+		if !up {
+			var down bool
+			if base, down = strings.CutSuffix(fileName, ".down.sql"); !down {
+				continue
+			}
+		}
+		versionStr, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("migration file %s must start with a version number", fileName)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return err
+		}
+
+		s, ok := found[version]
+		if !ok {
+			s = &scripts{name: name}
+			found[version] = s
+			versions = append(versions, version)
+		}
+		if s.name != name {
+			return fmt.Errorf("migration %d is named both %s and %s", version, s.name, name)
+		}
+		if up {
+			if s.hasUp {
+				return fmt.Errorf("migration %d has more than one up script", version)
+			}
+			s.up, s.hasUp = string(content), true
+		} else {
+			if s.hasDown {
+				return fmt.Errorf("migration %d has more than one down script", version)
+			}
+			s.down, s.hasDown = string(content), true
+		}
+	}
+
+	slices.Sort(versions)
+	migrations := make([]Migration, len(versions))
+	for i, version := range versions {
+		s := found[version]
+		if len(strings.TrimSpace(s.up)) == 0 {
+			return fmt.Errorf("migration %d has no up script", version)
+		}
+		migrations[i] = SQLMigration(version, s.name, s.up, s.down)
+	}
+	return m.Register(migrations...)
+}
+
+// Up applies every registered migration that has not been applied yet.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.migrate(ctx, func(tx Transaction, applied []*appliedMigration) error {
+		done := make(map[int64]struct{}, len(applied))
+		for _, a := range applied {
+			done[a.Version] = struct{}{}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := migration.Up(ctx, tx); err != nil {
+				return fmt.Errorf("migration %d %s failed: %w", migration.Version, migration.Name, err)
+			}
+			insertSql := fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", migrationTable)
+			if _, err := tx.ExecCtx(ctx, insertSql, migration.Version, migration.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the n most recently applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	return m.migrate(ctx, func(tx Transaction, applied []*appliedMigration) error {
+		for i := len(applied) - 1; i >= 0 && i >= len(applied)-n; i-- {
+			version := applied[i].Version
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("applied migration %d is not registered", version)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("migration %d %s cannot be reverted", version, migration.Name)
+			}
+			if err := migration.Down(ctx, tx); err != nil {
+				return fmt.Errorf("revert migration %d %s failed: %w", version, migration.Name, err)
+			}
+			deleteSql := fmt.Sprintf("DELETE FROM %s WHERE version = $1", migrationTable)
+			if _, err := tx.ExecCtx(ctx, deleteSql, version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status lists the registered and applied migrations in version order. It
+// only reads schema_migrations, without taking the migration lock, and
+// reports nothing as applied when the table does not exist yet.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, m.d)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTableCode {
+		applied, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(m.migrations)+len(applied))
+	for _, migration := range m.migrations {
+		status = append(status, MigrationStatus{Version: migration.Version, Name: migration.Name})
+	}
+	for _, a := range applied {
+		i, ok := slices.BinarySearchFunc(status, a.Version, func(s MigrationStatus, v int64) int {
+			return cmp.Compare(s.Version, v)
+		})
+		if !ok {
+			status = slices.Insert(status, i, MigrationStatus{Version: a.Version, Name: a.Name})
+		}
+		status[i].Applied = true
+		status[i].AppliedAt = a.AppliedAt
+	}
+	return status, nil
+}
+
+// migrate runs f in a transaction that holds the migration lock, passing it
+// the applied migrations in version order.
+func (m *Migrator) migrate(ctx context.Context, f func(tx Transaction, applied []*appliedMigration) error) error {
+	return WithTxCtx(ctx, m.d, func(tx Transaction) error {
+		if _, err := tx.ExecCtx(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockKey); err != nil {
+			return err
+		}
+		if _, err := tx.ExecCtx(ctx, createMigrationTableSql); err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return f(tx, applied)
+	})
+}
+
+// applied reads the applied migrations from schema_migrations in version
+// order.
+func (m *Migrator) applied(ctx context.Context, q Querier) ([]*appliedMigration, error) {
+	p := m.d.GetDBPattern()
+	query := fmt.Sprintf("SELECT version AS %s, name AS %s, applied_at AS %s FROM %s ORDER BY version",
+		p.ColumnName("Version"), p.ColumnName("Name"), p.ColumnName("AppliedAt"), migrationTable)
+	var applied []*appliedMigration
+	if err := q.SelectCtx(ctx, &applied, query); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// GenerateMigrationSQL returns up and down scripts that create and drop the
+// tables of models, to be saved as the first migration of a project.
+func (s *DB) GenerateMigrationSQL(models ...any) (up string, down string, err error) {
+	if up, err = s.SchemaSQL(models...); err != nil {
+		return "", "", err
+	}
+	schemas, err := s.buildTableSchemas(models)
+	if err != nil {
+		return "", "", err
+	}
+	// Foreign keys closing a cycle are dropped first, so that the tables can
+	// be dropped in reverse order.
+	var downSql []string
+	for _, schema := range schemas {
+		for _, column := range schema.columns {
+			if column.deferFK {
+				downSql = append(downSql, fmt.Sprintf("ALTER TABLE IF EXISTS %s DROP CONSTRAINT IF EXISTS %s_%s_fkey;",
+					schema.field.TableName, schema.field.TableName, column.name))
+			}
+		}
+	}
+	for i := len(schemas) - 1; i >= 0; i-- {
+		downSql = append(downSql, fmt.Sprintf("DROP TABLE IF EXISTS %s;", schemas[i].field.TableName))
+	}
+	return up, strings.Join(downSql, "\n") + "\n", nil
+}
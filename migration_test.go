@@ -0,0 +1,197 @@
+package korm
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type migrationDriver struct {
+	Driver
+	tx *migrationTx
+}
+
+func (d migrationDriver) BeginCtx(context.Context) (Transaction, error) {
+	return d.tx, nil
+}
+
+func (d migrationDriver) GetDBPattern() DBPattern {
+	return defaultPattern
+}
+
+// SelectCtx reads schema_migrations outside a transaction, failing like
+// Postgres until the table has been created.
+func (d migrationDriver) SelectCtx(ctx context.Context, target any, query string, args ...any) error {
+	if !slices.Contains(d.tx.sqlList, createMigrationTableSql) {
+		return &pgconn.PgError{Code: undefinedTableCode, Message: `relation "schema_migrations" does not exist`}
+	}
+	return d.tx.SelectCtx(ctx, target, query, args...)
+}
+
+// migrationTx keeps schema_migrations in memory and records every other
+// statement it runs.
+type migrationTx struct {
+	fakeTx
+	applied []*appliedMigration
+	sqlList []string
+}
+
+func (tx *migrationTx) ExecCtx(_ context.Context, query string, args ...any) (int64, error) {
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		tx.applied = append(tx.applied, &appliedMigration{Version: args[0].(int64), Name: args[1].(string)})
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		tx.applied = slices.DeleteFunc(tx.applied, func(a *appliedMigration) bool {
+			return a.Version == args[0].(int64)
+		})
+	default:
+		tx.sqlList = append(tx.sqlList, query)
+	}
+	return 0, nil
+}
+
+func (tx *migrationTx) SelectCtx(_ context.Context, target any, _ string, _ ...any) error {
+	*target.(*[]*appliedMigration) = slices.Clone(tx.applied)
+	return nil
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	tx := &migrationTx{}
+	m := NewMigrator(migrationDriver{tx: tx})
+
+	fsys := fstest.MapFS{
+		"migrations/2_add_email.up.sql":      {Data: []byte("ALTER TABLE student ADD COLUMN email TEXT;")},
+		"migrations/2_add_email.down.sql":    {Data: []byte("ALTER TABLE student DROP COLUMN email;")},
+		"migrations/1_create_student.up.sql": {Data: []byte("CREATE TABLE student (id BIGINT PRIMARY KEY);")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+	}
+	require.NoError(t, m.RegisterFS(fsys, "migrations"))
+	require.NoError(t, m.Register(Migration{
+		Version: 3,
+		Name:    "seed",
+		Up: func(ctx context.Context, tx Transaction) error {
+			_, err := tx.ExecCtx(ctx, "INSERT INTO student (id) VALUES (1)")
+			return err
+		},
+	}))
+	require.EqualError(t, m.Register(SQLMigration(2, "again", "SELECT 1", "")), "migration 2 already registered")
+
+	// Nothing is applied before schema_migrations exists, and Status does not
+	// create it.
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	for _, s := range status {
+		assert.False(t, s.Applied)
+	}
+	assert.Empty(t, tx.sqlList)
+	assert.False(t, tx.committed)
+
+	require.NoError(t, m.Up(ctx))
+	assert.True(t, tx.committed)
+	assert.Equal(t, []string{
+		"SELECT pg_advisory_xact_lock($1)",
+		createMigrationTableSql,
+		"CREATE TABLE student (id BIGINT PRIMARY KEY);",
+		"ALTER TABLE student ADD COLUMN email TEXT;",
+		"INSERT INTO student (id) VALUES (1)",
+	}, tx.sqlList)
+
+	ran := len(tx.sqlList)
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tx.sqlList, ran)
+	require.Len(t, status, 3)
+	for i, s := range status {
+		assert.Equal(t, int64(i+1), s.Version)
+		assert.True(t, s.Applied)
+	}
+	assert.Equal(t, "create_student", status[0].Name)
+
+	// The seed migration has no down step, so nothing is reverted.
+	tx.sqlList = nil
+	require.EqualError(t, m.Down(ctx, 2), "migration 3 seed cannot be reverted")
+	assert.True(t, tx.rollbacked)
+
+	// Forget the seed migration so that the one before it can be reverted.
+	tx.applied = tx.applied[:2]
+	tx.sqlList = nil
+	require.NoError(t, m.Down(ctx, 1))
+	assert.Contains(t, tx.sqlList, "ALTER TABLE student DROP COLUMN email;")
+
+	status, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+	assert.False(t, status[2].Applied)
+}
+
+func TestMigratorUpFailed(t *testing.T) {
+	fnErr := errors.New("boom")
+	tx := &migrationTx{}
+	m := NewMigrator(migrationDriver{tx: tx})
+	require.NoError(t, m.Register(Migration{
+		Version: 1,
+		Name:    "broken",
+		Up:      func(context.Context, Transaction) error { return fnErr },
+	}))
+
+	err := m.Up(context.Background())
+	require.ErrorIs(t, err, fnErr)
+	assert.True(t, tx.rollbacked)
+	assert.False(t, tx.committed)
+}
+
+func TestMigratorRegisterFS(t *testing.T) {
+	m := NewMigrator(nil)
+	err := m.RegisterFS(fstest.MapFS{"init.up.sql": {Data: []byte("SELECT 1")}}, ".")
+	require.EqualError(t, err, "migration file init.up.sql must start with a version number")
+
+	err = m.RegisterFS(fstest.MapFS{"1_init.down.sql": {Data: []byte("SELECT 1")}}, ".")
+	require.EqualError(t, err, "migration 1 has no up script")
+
+	err = m.RegisterFS(fstest.MapFS{
+		"3_add_a.up.sql": {Data: []byte("SELECT 1")},
+		"3_add_b.up.sql": {Data: []byte("SELECT 2")},
+	}, ".")
+	require.EqualError(t, err, "migration 3 is named both add_a and add_b")
+
+	err = m.RegisterFS(fstest.MapFS{
+		"3_add_a.up.sql":   {Data: []byte("SELECT 1")},
+		"03_add_a.up.sql":  {Data: []byte("SELECT 2")},
+		"3_add_a.down.sql": {Data: []byte("SELECT 3")},
+	}, ".")
+	require.EqualError(t, err, "migration 3 has more than one up script")
+}
+
+func TestDB_GenerateMigrationSQL(t *testing.T) {
+	db := initDB(nil)
+	up, down, err := db.GenerateMigrationSQL(IdentityModel{}, CompositeModel{})
+	require.NoError(t, err)
+	assert.Equal(t, `CREATE TABLE IF NOT EXISTS identity_model (
+id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+name TEXT
+);
+
+CREATE TABLE IF NOT EXISTS composite_model (
+tenant_id BIGINT,
+user_id BIGINT,
+name TEXT,
+PRIMARY KEY (tenant_id, user_id)
+);
+`, up)
+	assert.Equal(t, "DROP TABLE IF EXISTS composite_model;\nDROP TABLE IF EXISTS identity_model;\n", down)
+
+	_, down, err = db.GenerateMigrationSQL(FkCycleA{}, FkCycleB{})
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE IF EXISTS fk_cycle_b DROP CONSTRAINT IF EXISTS fk_cycle_b_fk_cycle_a_fkey;\n"+
+		"DROP TABLE IF EXISTS fk_cycle_a;\nDROP TABLE IF EXISTS fk_cycle_b;\n", down)
+}
@@ -0,0 +1,378 @@
+package otelkorm
+
+import (
+	"context"
+
+	"github.com/Kseleven/korm"
+)
+
+// Wrap returns a korm.Driver that traces and measures every operation run on
+// d and on the transactions it begins.
+func Wrap(d korm.Driver, opts ...Option) (korm.Driver, error) {
+	inst, err := newInstrumentation(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &driver{Driver: d, inst: inst}, nil
+}
+
+type driver struct {
+	korm.Driver
+	inst *instrumentation
+}
+
+func (d *driver) Begin() (korm.Transaction, error) {
+	return d.BeginCtx(context.Background())
+}
+
+func (d *driver) BeginCtx(ctx context.Context) (korm.Transaction, error) {
+	var tx korm.Transaction
+	_, err := d.inst.observe(ctx, "begin", "", "", func(ctx context.Context) (int64, error) {
+		var err error
+		tx, err = d.Driver.BeginCtx(ctx)
+		return -1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Transaction: tx, driver: d.Driver, inst: d.inst}, nil
+}
+
+func (d *driver) BeginTx(ctx context.Context, opts korm.TxOptions) (korm.Transaction, error) {
+	var tx korm.Transaction
+	_, err := d.inst.observe(ctx, "begin", "", "", func(ctx context.Context) (int64, error) {
+		var err error
+		tx, err = d.Driver.BeginTx(ctx, opts)
+		return -1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Transaction: tx, driver: d.Driver, inst: d.inst}, nil
+}
+
+func (d *driver) Insert(data any) error {
+	return d.InsertCtx(context.Background(), data)
+}
+
+func (d *driver) InsertCtx(ctx context.Context, data any) error {
+	return insert(ctx, d.inst, d.Driver, d.Driver, data)
+}
+
+func (d *driver) InsertReturning(data any) error {
+	return d.InsertReturningCtx(context.Background(), data)
+}
+
+func (d *driver) InsertReturningCtx(ctx context.Context, data any) error {
+	return insertReturning(ctx, d.inst, d.Driver, d.Driver, data)
+}
+
+func (d *driver) Select(target any, query string, args ...any) error {
+	return d.SelectCtx(context.Background(), target, query, args...)
+}
+
+func (d *driver) SelectCtx(ctx context.Context, target any, query string, args ...any) error {
+	return selectRows(ctx, d.inst, d.Driver, d.Driver, target, query, args...)
+}
+
+func (d *driver) Get(dest any, query string, args ...any) error {
+	return d.GetCtx(context.Background(), dest, query, args...)
+}
+
+func (d *driver) GetCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return getRow(ctx, d.inst, d.Driver, d.Driver, dest, query, args...)
+}
+
+func (d *driver) First(dest any, query string, args ...any) error {
+	return d.FirstCtx(context.Background(), dest, query, args...)
+}
+
+func (d *driver) FirstCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return firstRow(ctx, d.inst, d.Driver, d.Driver, dest, query, args...)
+}
+
+func (d *driver) FindByPK(dest any, keys ...any) error {
+	return d.FindByPKCtx(context.Background(), dest, keys...)
+}
+
+func (d *driver) FindByPKCtx(ctx context.Context, dest any, keys ...any) error {
+	return findByPK(ctx, d.inst, d.Driver, d.Driver, dest, keys...)
+}
+
+func (d *driver) Update(model any) (int64, error) {
+	return d.UpdateCtx(context.Background(), model)
+}
+
+func (d *driver) UpdateCtx(ctx context.Context, model any) (int64, error) {
+	return update(ctx, d.inst, d.Driver, d.Driver, model)
+}
+
+func (d *driver) UpdateColumns(model any, columns ...string) (int64, error) {
+	return d.UpdateColumnsCtx(context.Background(), model, columns...)
+}
+
+func (d *driver) UpdateColumnsCtx(ctx context.Context, model any, columns ...string) (int64, error) {
+	return d.inst.observe(ctx, "update", tableName(d.Driver, model), "", func(ctx context.Context) (int64, error) {
+		return d.Driver.UpdateColumnsCtx(ctx, model, columns...)
+	})
+}
+
+func (d *driver) UpdateFields(model any, fields map[string]any) (int64, error) {
+	return d.UpdateFieldsCtx(context.Background(), model, fields)
+}
+
+func (d *driver) UpdateFieldsCtx(ctx context.Context, model any, fields map[string]any) (int64, error) {
+	return d.inst.observe(ctx, "update", tableName(d.Driver, model), "", func(ctx context.Context) (int64, error) {
+		return d.Driver.UpdateFieldsCtx(ctx, model, fields)
+	})
+}
+
+func (d *driver) Upsert(data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return d.UpsertCtx(context.Background(), data, conflictColumns, updateColumns)
+}
+
+func (d *driver) UpsertCtx(ctx context.Context, data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return d.inst.observe(ctx, "upsert", tableName(d.Driver, data), "", func(ctx context.Context) (int64, error) {
+		return d.Driver.UpsertCtx(ctx, data, conflictColumns, updateColumns)
+	})
+}
+
+func (d *driver) Delete(model any) (int64, error) {
+	return d.DeleteCtx(context.Background(), model)
+}
+
+func (d *driver) DeleteCtx(ctx context.Context, model any) (int64, error) {
+	return d.inst.observe(ctx, "delete", tableName(d.Driver, model), "", func(ctx context.Context) (int64, error) {
+		return d.Driver.DeleteCtx(ctx, model)
+	})
+}
+
+func (d *driver) Exec(query string, args ...any) (int64, error) {
+	return d.ExecCtx(context.Background(), query, args...)
+}
+
+func (d *driver) ExecCtx(ctx context.Context, query string, args ...any) (int64, error) {
+	return exec(ctx, d.inst, d.Driver, query, args...)
+}
+
+type transaction struct {
+	korm.Transaction
+	driver korm.Driver
+	inst   *instrumentation
+}
+
+func (tx *transaction) Begin() (korm.Transaction, error) {
+	return tx.BeginCtx(context.Background())
+}
+
+func (tx *transaction) BeginCtx(ctx context.Context) (korm.Transaction, error) {
+	var nested korm.Transaction
+	_, err := tx.inst.observe(ctx, "savepoint", "", "", func(ctx context.Context) (int64, error) {
+		var err error
+		nested, err = tx.Transaction.BeginCtx(ctx)
+		return -1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Transaction: nested, driver: tx.driver, inst: tx.inst}, nil
+}
+
+func (tx *transaction) Commit() error {
+	return tx.CommitCtx(context.Background())
+}
+
+func (tx *transaction) CommitCtx(ctx context.Context) error {
+	_, err := tx.inst.observe(ctx, "commit", "", "", func(ctx context.Context) (int64, error) {
+		return -1, tx.Transaction.CommitCtx(ctx)
+	})
+	return err
+}
+
+func (tx *transaction) Rollback() error {
+	return tx.RollbackCtx(context.Background())
+}
+
+func (tx *transaction) RollbackCtx(ctx context.Context) error {
+	_, err := tx.inst.observe(ctx, "rollback", "", "", func(ctx context.Context) (int64, error) {
+		return -1, tx.Transaction.RollbackCtx(ctx)
+	})
+	return err
+}
+
+func (tx *transaction) Insert(data any) error {
+	return tx.InsertCtx(context.Background(), data)
+}
+
+func (tx *transaction) InsertCtx(ctx context.Context, data any) error {
+	return insert(ctx, tx.inst, tx.driver, tx.Transaction, data)
+}
+
+func (tx *transaction) InsertReturning(data any) error {
+	return tx.InsertReturningCtx(context.Background(), data)
+}
+
+func (tx *transaction) InsertReturningCtx(ctx context.Context, data any) error {
+	return insertReturning(ctx, tx.inst, tx.driver, tx.Transaction, data)
+}
+
+func (tx *transaction) Select(target any, query string, args ...any) error {
+	return tx.SelectCtx(context.Background(), target, query, args...)
+}
+
+func (tx *transaction) SelectCtx(ctx context.Context, target any, query string, args ...any) error {
+	return selectRows(ctx, tx.inst, tx.driver, tx.Transaction, target, query, args...)
+}
+
+func (tx *transaction) Get(dest any, query string, args ...any) error {
+	return tx.GetCtx(context.Background(), dest, query, args...)
+}
+
+func (tx *transaction) GetCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return getRow(ctx, tx.inst, tx.driver, tx.Transaction, dest, query, args...)
+}
+
+func (tx *transaction) First(dest any, query string, args ...any) error {
+	return tx.FirstCtx(context.Background(), dest, query, args...)
+}
+
+func (tx *transaction) FirstCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return firstRow(ctx, tx.inst, tx.driver, tx.Transaction, dest, query, args...)
+}
+
+func (tx *transaction) FindByPK(dest any, keys ...any) error {
+	return tx.FindByPKCtx(context.Background(), dest, keys...)
+}
+
+func (tx *transaction) FindByPKCtx(ctx context.Context, dest any, keys ...any) error {
+	return findByPK(ctx, tx.inst, tx.driver, tx.Transaction, dest, keys...)
+}
+
+func (tx *transaction) Update(model any) (int64, error) {
+	return tx.UpdateCtx(context.Background(), model)
+}
+
+func (tx *transaction) UpdateCtx(ctx context.Context, model any) (int64, error) {
+	return update(ctx, tx.inst, tx.driver, tx.Transaction, model)
+}
+
+func (tx *transaction) UpdateColumns(model any, columns ...string) (int64, error) {
+	return tx.UpdateColumnsCtx(context.Background(), model, columns...)
+}
+
+func (tx *transaction) UpdateColumnsCtx(ctx context.Context, model any, columns ...string) (int64, error) {
+	return tx.inst.observe(ctx, "update", tableName(tx.driver, model), "", func(ctx context.Context) (int64, error) {
+		return tx.Transaction.UpdateColumnsCtx(ctx, model, columns...)
+	})
+}
+
+func (tx *transaction) UpdateFields(model any, fields map[string]any) (int64, error) {
+	return tx.UpdateFieldsCtx(context.Background(), model, fields)
+}
+
+func (tx *transaction) UpdateFieldsCtx(ctx context.Context, model any, fields map[string]any) (int64, error) {
+	return tx.inst.observe(ctx, "update", tableName(tx.driver, model), "", func(ctx context.Context) (int64, error) {
+		return tx.Transaction.UpdateFieldsCtx(ctx, model, fields)
+	})
+}
+
+func (tx *transaction) Upsert(data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return tx.UpsertCtx(context.Background(), data, conflictColumns, updateColumns)
+}
+
+func (tx *transaction) UpsertCtx(ctx context.Context, data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return tx.inst.observe(ctx, "upsert", tableName(tx.driver, data), "", func(ctx context.Context) (int64, error) {
+		return tx.Transaction.UpsertCtx(ctx, data, conflictColumns, updateColumns)
+	})
+}
+
+func (tx *transaction) Delete(model any) (int64, error) {
+	return tx.DeleteCtx(context.Background(), model)
+}
+
+func (tx *transaction) DeleteCtx(ctx context.Context, model any) (int64, error) {
+	return tx.inst.observe(ctx, "delete", tableName(tx.driver, model), "", func(ctx context.Context) (int64, error) {
+		return tx.Transaction.DeleteCtx(ctx, model)
+	})
+}
+
+func (tx *transaction) Exec(query string, args ...any) (int64, error) {
+	return tx.ExecCtx(context.Background(), query, args...)
+}
+
+func (tx *transaction) ExecCtx(ctx context.Context, query string, args ...any) (int64, error) {
+	return exec(ctx, tx.inst, tx.Transaction, query, args...)
+}
+
+func insert(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, data any) error {
+	_, err := inst.observe(ctx, "insert", tableName(d, data), "", func(ctx context.Context) (int64, error) {
+		if err := q.InsertCtx(ctx, data); err != nil {
+			return 0, err
+		}
+		return rowCount(data), nil
+	})
+	return err
+}
+
+func insertReturning(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, data any) error {
+	_, err := inst.observe(ctx, "insert", tableName(d, data), "", func(ctx context.Context) (int64, error) {
+		if err := q.InsertReturningCtx(ctx, data); err != nil {
+			return 0, err
+		}
+		return rowCount(data), nil
+	})
+	return err
+}
+
+func selectRows(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, target any, query string, args ...any) error {
+	_, err := inst.observe(ctx, "select", tableName(d, target), query, func(ctx context.Context) (int64, error) {
+		before := rowCount(target)
+		if err := q.SelectCtx(ctx, target, query, args...); err != nil {
+			return 0, err
+		}
+		return rowCount(target) - before, nil
+	})
+	return err
+}
+
+func getRow(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, dest any, query string, args ...any) error {
+	_, err := inst.observe(ctx, "select", tableName(d, dest), query, func(ctx context.Context) (int64, error) {
+		if err := q.GetCtx(ctx, dest, query, args...); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+	return err
+}
+
+func firstRow(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, dest any, query string, args ...any) error {
+	_, err := inst.observe(ctx, "select", tableName(d, dest), query, func(ctx context.Context) (int64, error) {
+		if err := q.FirstCtx(ctx, dest, query, args...); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+	return err
+}
+
+func findByPK(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, dest any, keys ...any) error {
+	_, err := inst.observe(ctx, "select", tableName(d, dest), "", func(ctx context.Context) (int64, error) {
+		if err := q.FindByPKCtx(ctx, dest, keys...); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+	return err
+}
+
+func update(ctx context.Context, inst *instrumentation, d korm.Driver, q korm.Querier, model any) (int64, error) {
+	return inst.observe(ctx, "update", tableName(d, model), "", func(ctx context.Context) (int64, error) {
+		return q.UpdateCtx(ctx, model)
+	})
+}
+
+func exec(ctx context.Context, inst *instrumentation, q korm.Querier, query string, args ...any) (int64, error) {
+	return inst.observe(ctx, "exec", "", query, func(ctx context.Context) (int64, error) {
+		return q.ExecCtx(ctx, query, args...)
+	})
+}
@@ -0,0 +1,158 @@
+package otelkorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kseleven/korm"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type Student struct {
+	Id   int
+	Name string
+}
+
+type fakeDriver struct {
+	korm.Driver
+	execErr error
+}
+
+type snakePattern struct{}
+
+func (snakePattern) TableName(t string) string {
+	return korm.ToSnake(t)
+}
+
+func (snakePattern) ColumnName(t string) string {
+	return korm.ToSnake(t)
+}
+
+func (d *fakeDriver) GetDBPattern() korm.DBPattern {
+	return snakePattern{}
+}
+
+func (d *fakeDriver) GetTableCache(name string) (*korm.Field, bool) {
+	if name != "student" {
+		return nil, false
+	}
+	return &korm.Field{TableName: "student"}, true
+}
+
+func (d *fakeDriver) BeginCtx(context.Context) (korm.Transaction, error) {
+	return &fakeTx{driver: d}, nil
+}
+
+func (d *fakeDriver) InsertCtx(context.Context, any) error {
+	return nil
+}
+
+func (d *fakeDriver) SelectCtx(_ context.Context, target any, _ string, _ ...any) error {
+	result := target.(*[]*Student)
+	*result = append(*result, &Student{Id: 1}, &Student{Id: 2})
+	return nil
+}
+
+func (d *fakeDriver) ExecCtx(context.Context, string, ...any) (int64, error) {
+	return 0, d.execErr
+}
+
+type fakeTx struct {
+	korm.Transaction
+	driver *fakeDriver
+}
+
+func (tx *fakeTx) InsertCtx(ctx context.Context, data any) error {
+	return tx.driver.InsertCtx(ctx, data)
+}
+
+func (tx *fakeTx) ExecCtx(ctx context.Context, query string, args ...any) (int64, error) {
+	return tx.driver.ExecCtx(ctx, query, args...)
+}
+
+func (tx *fakeTx) CommitCtx(context.Context) error {
+	return nil
+}
+
+func (tx *fakeTx) RollbackCtx(context.Context) error {
+	return nil
+}
+
+func newTestDriver(t *testing.T, d korm.Driver) (korm.Driver, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	recorder := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	wrapped, err := Wrap(d,
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	require.NoError(t, err)
+	return wrapped, recorder, reader
+}
+
+func TestWrap_Transaction(t *testing.T) {
+	d, recorder, reader := newTestDriver(t, &fakeDriver{})
+	require.NoError(t, korm.WithTx(d, func(tx korm.Transaction) error {
+		return tx.Insert([]*Student{{Id: 1}, {Id: 2}, {Id: 3}})
+	}))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "begin", spans[0].Name())
+	assert.Equal(t, "insert student", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.collection.name", "student"))
+	assert.Equal(t, "commit", spans[2].Name())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	rows := findMetric(t, rm, "db.client.response.returned_rows").Data.(metricdata.Histogram[int64])
+	require.Len(t, rows.DataPoints, 1)
+	assert.Equal(t, int64(3), rows.DataPoints[0].Sum)
+}
+
+func TestWrap_Select(t *testing.T) {
+	d, recorder, _ := newTestDriver(t, &fakeDriver{})
+	var result []*Student
+	require.NoError(t, d.Select(&result, "SELECT * FROM student"))
+	require.Len(t, result, 2)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "select student", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.query.text", "SELECT * FROM student"))
+}
+
+func TestWrap_Error(t *testing.T) {
+	d, recorder, reader := newTestDriver(t, &fakeDriver{execErr: &pgconn.PgError{Code: "40001"}})
+	_, err := d.Exec("UPDATE student SET name = 'a'")
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.response.status_code", "40001"))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	errs := findMetric(t, rm, "db.client.operation.errors").Data.(metricdata.Sum[int64])
+	require.Len(t, errs.DataPoints, 1)
+	assert.Equal(t, int64(1), errs.DataPoints[0].Value)
+}
+
+func findMetric(t *testing.T, rm metricdata.ResourceMetrics, name string) metricdata.Metrics {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return metricdata.Metrics{}
+}
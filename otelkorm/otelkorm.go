@@ -0,0 +1,153 @@
+// Package otelkorm wraps a korm.Driver and its transactions to emit
+// OpenTelemetry spans and metrics for every database operation.
+package otelkorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/Kseleven/korm"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.30.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Kseleven/korm/otelkorm"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+type Option func(*config)
+
+// WithTracerProvider sets the provider used to create spans. The global
+// provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the provider used to create metrics. The global
+// provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+type instrumentation struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	rows     metric.Int64Histogram
+	errors   metric.Int64Counter
+}
+
+func newInstrumentation(opts ...Option) (*instrumentation, error) {
+	c := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram("db.client.operation.duration",
+		metric.WithDescription("Duration of database client operations."), metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := meter.Int64Histogram("db.client.response.returned_rows",
+		metric.WithDescription("Rows inserted, returned or affected by an operation."), metric.WithUnit("{row}"))
+	if err != nil {
+		return nil, err
+	}
+	errCounter, err := meter.Int64Counter("db.client.operation.errors",
+		metric.WithDescription("Number of failed database client operations."), metric.WithUnit("{error}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &instrumentation{
+		tracer:   c.tracerProvider.Tracer(instrumentationName),
+		duration: duration,
+		rows:     rows,
+		errors:   errCounter,
+	}, nil
+}
+
+// observe runs f inside a client span named after op and table and records
+// its duration, row count and errors.
+func (i *instrumentation) observe(ctx context.Context, op, table, query string, f func(ctx context.Context) (int64, error)) (int64, error) {
+	attrs := []attribute.KeyValue{semconv.DBSystemNamePostgreSQL, semconv.DBOperationName(op)}
+	if table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(table))
+	}
+	spanName := op
+	if table != "" {
+		spanName += " " + table
+	}
+
+	spanAttrs := attrs
+	if query != "" {
+		spanAttrs = append(spanAttrs[:len(spanAttrs):len(spanAttrs)], semconv.DBQueryText(query))
+	}
+	ctx, span := i.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(spanAttrs...))
+	defer span.End()
+
+	start := time.Now()
+	n, err := f(ctx)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			attrs = append(attrs, semconv.DBResponseStatusCode(pgErr.Code))
+			span.SetAttributes(semconv.DBResponseStatusCode(pgErr.Code))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		i.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+	} else if n >= 0 {
+		i.rows.Record(ctx, n, metric.WithAttributes(attrs...))
+	}
+	i.duration.Record(ctx, elapsed.Seconds(), metric.WithAttributes(attrs...))
+	return n, err
+}
+
+// tableName looks up the registered table for the struct behind data, which
+// may be a struct pointer or a (pointer to a) slice of struct pointers.
+func tableName(d korm.Driver, data any) string {
+	t := reflect.TypeOf(data)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return ""
+	}
+	if field, ok := d.GetTableCache(d.GetDBPattern().TableName(t.Name())); ok {
+		return field.TableName
+	}
+	return ""
+}
+
+// rowCount returns the number of elements in data when it is a slice or a
+// pointer to one, and 1 otherwise.
+func rowCount(data any) int64 {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		return int64(v.Len())
+	}
+	return 1
+}
@@ -2,22 +2,226 @@ package korm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrNotFound     = errors.New("korm: no rows found")
+	ErrMultipleRows = errors.New("korm: query returned more than one row")
+)
+
+// Select runs query on q and scans every row into a new T.
+func Select[T any](q Querier, query string, args ...any) ([]*T, error) {
+	return SelectCtx[T](context.Background(), q, query, args...)
+}
+
+func SelectCtx[T any](ctx context.Context, q Querier, query string, args ...any) ([]*T, error) {
+	var result []*T
+	if err := q.SelectCtx(ctx, &result, query, args...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get runs query on q and expects exactly one row. It returns ErrNotFound
+// when there are no rows and ErrMultipleRows when there is more than one.
+func Get[T any](q Querier, query string, args ...any) (*T, error) {
+	return GetCtx[T](context.Background(), q, query, args...)
+}
+
+func GetCtx[T any](ctx context.Context, q Querier, query string, args ...any) (*T, error) {
+	result := new(T)
+	if err := q.GetCtx(ctx, result, query, args...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// First runs query on q and returns its first row, or ErrNotFound when there
+// are no rows. Only the first row is scanned.
+func First[T any](q Querier, query string, args ...any) (*T, error) {
+	return FirstCtx[T](context.Background(), q, query, args...)
+}
+
+func FirstCtx[T any](ctx context.Context, q Querier, query string, args ...any) (*T, error) {
+	result := new(T)
+	if err := q.FirstCtx(ctx, result, query, args...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *DB) Select(target any, query string, args ...any) error {
+	return s.SelectCtx(context.Background(), target, query, args...)
+}
+
+func (s *DB) SelectCtx(ctx context.Context, target any, query string, args ...any) error {
+	return selectRows(ctx, s, s.conn, target, query, args...)
+}
+
 func (tx DBTx) Select(target any, query string, args ...any) error {
-	rows, err := tx.Tx.Query(context.Background(), query, args...)
+	return tx.SelectCtx(context.Background(), target, query, args...)
+}
+
+func (tx DBTx) SelectCtx(ctx context.Context, target any, query string, args ...any) error {
+	return selectRows(ctx, tx.Driver, tx.Tx, target, query, args...)
+}
+
+func (s *DB) Get(dest any, query string, args ...any) error {
+	return s.GetCtx(context.Background(), dest, query, args...)
+}
+
+func (s *DB) GetCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return getRow(ctx, s, s.conn, dest, query, args...)
+}
+
+func (tx DBTx) Get(dest any, query string, args ...any) error {
+	return tx.GetCtx(context.Background(), dest, query, args...)
+}
+
+func (tx DBTx) GetCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return getRow(ctx, tx.Driver, tx.Tx, dest, query, args...)
+}
+
+func (s *DB) First(dest any, query string, args ...any) error {
+	return s.FirstCtx(context.Background(), dest, query, args...)
+}
+
+func (s *DB) FirstCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return firstRow(ctx, s, s.conn, dest, query, args...)
+}
+
+func (tx DBTx) First(dest any, query string, args ...any) error {
+	return tx.FirstCtx(context.Background(), dest, query, args...)
+}
+
+func (tx DBTx) FirstCtx(ctx context.Context, dest any, query string, args ...any) error {
+	return firstRow(ctx, tx.Driver, tx.Tx, dest, query, args...)
+}
+
+// getRow scans exactly one row into dest, which must be a pointer to a
+// struct. It returns ErrNotFound or ErrMultipleRows otherwise, and stops
+// reading as soon as a second row shows up.
+func getRow(ctx context.Context, d Driver, conn dbConn, dest any, query string, args ...any) error {
+	n, err := scanFirstRows(ctx, d, conn, dest, 2, query, args...)
+	if err != nil {
+		return err
+	}
+	switch n {
+	case 0:
+		return ErrNotFound
+	case 1:
+		return nil
+	default:
+		return ErrMultipleRows
+	}
+}
+
+// firstRow scans the first row into dest, which must be a pointer to a
+// struct, and returns ErrNotFound when there is none.
+func firstRow(ctx context.Context, d Driver, conn dbConn, dest any, query string, args ...any) error {
+	n, err := scanFirstRows(ctx, d, conn, dest, 1, query, args...)
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanFirstRows reads at most limit rows of query, scans the first one into
+// dest and returns how many rows it read. The rest are discarded unscanned.
+func scanFirstRows(ctx context.Context, d Driver, conn dbConn, dest any, limit int, query string, args ...any) (int, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return 0, fmt.Errorf("dest must be a pointer to a struct")
+	}
+	scan := rowScanner(d, v.Type().Elem())
+
+	n := 0
+	err := queryRows(ctx, d, conn, query, args, func(rows pgx.Rows) error {
+		for n < limit && rows.Next() {
+			if n == 0 {
+				if err := scan(rows, v); err != nil {
+					return err
+				}
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (s *DB) FindByPK(dest any, keys ...any) error {
+	return s.FindByPKCtx(context.Background(), dest, keys...)
+}
+
+func (s *DB) FindByPKCtx(ctx context.Context, dest any, keys ...any) error {
+	return findByPK(ctx, s, s.conn, dest, keys...)
+}
+
+func (tx DBTx) FindByPK(dest any, keys ...any) error {
+	return tx.FindByPKCtx(context.Background(), dest, keys...)
+}
 
-	return tx.scanRows(rows, target)
+func (tx DBTx) FindByPKCtx(ctx context.Context, dest any, keys ...any) error {
+	return findByPK(ctx, tx.Driver, tx.Tx, dest, keys...)
 }
 
-func (tx DBTx) scanRows(rows pgx.Rows, target interface{}) error {
+// findByPK loads the row of dest's registered table whose primary key
+// equals keys, given in the order of Field.PrimaryKeys.
+func findByPK(ctx context.Context, d Driver, conn dbConn, dest any, keys ...any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dest must be a pointer to a struct")
+	}
+	field, err := lookupField(d, v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	query, err := field.selectByPKSql()
+	if err != nil {
+		return err
+	}
+	if len(keys) != len(field.PrimaryKeys) {
+		return fmt.Errorf("table %s expects %d primary key values, got %d", field.TableName, len(field.PrimaryKeys), len(keys))
+	}
+	return getRow(ctx, d, conn, dest, query, keys...)
+}
+
+func selectRows(ctx context.Context, d Driver, conn dbConn, target any, query string, args ...any) error {
+	return queryRows(ctx, d, conn, query, args, func(rows pgx.Rows) error {
+		return scanRows(d, rows, target)
+	})
+}
+
+// queryRows runs query and passes its rows to f. The rows are closed
+// afterwards, even when f stops before reading all of them.
+func queryRows(ctx context.Context, d Driver, conn dbConn, query string, args []any, f func(rows pgx.Rows) error) error {
+	event := &QueryEvent{Op: OpSelect, SQL: query, Args: args}
+	_, err := traceQuery(ctx, d.GetTracer(), event, func(ctx context.Context) (int64, error) {
+		rows, err := conn.Query(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		if err := f(rows); err != nil {
+			return 0, err
+		}
+		rows.Close()
+		return rows.CommandTag().RowsAffected(), rows.Err()
+	})
+	return err
+}
+
+func scanRows(s Driver, rows pgx.Rows, target interface{}) error {
 	v := reflect.ValueOf(target)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
 		return fmt.Errorf("target must be a pointer to a slice")
@@ -28,12 +232,28 @@ func (tx DBTx) scanRows(rows pgx.Rows, target interface{}) error {
 	if t.Kind() != reflect.Ptr {
 		return fmt.Errorf("target element must be a pointer")
 	}
-	t = t.Elem()
+	scan := rowScanner(s, t.Elem())
+	for rows.Next() {
+		e := reflect.New(t.Elem())
+		if err := scan(rows, e); err != nil {
+			return err
+		}
+		v.Set(reflect.Append(v, e))
+	}
+
+	return nil
+}
+
+// rowScanner returns a function that scans the current row into dest, a
+// pointer to a t, matching columns to fields by name.
+func rowScanner(s Driver, t reflect.Type) func(rows pgx.Rows, dest reflect.Value) error {
 	fieldMap := make(map[string]string, t.NumField())
-	s := tx.Driver
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		if field.Tag.Get("db") == "-" {
+		// Destinations need not be registered models and may carry db tags
+		// meant for other libraries, so only db:"-" is looked at here.
+		tag, _ := parseTag(field.Tag.Get("db"))
+		if _, ok := tag["-"]; ok {
 			continue
 		}
 		if isFieldEmbed(field) {
@@ -46,20 +266,14 @@ func (tx DBTx) scanRows(rows pgx.Rows, target interface{}) error {
 		fieldMap[s.GetDBPattern().ColumnName(field.Name)] = field.Name
 	}
 
-	fds := rows.FieldDescriptions()
-	for rows.Next() {
-		e := reflect.New(t)
+	return func(rows pgx.Rows, dest reflect.Value) error {
+		fds := rows.FieldDescriptions()
 		scanTargets := make([]any, 0, len(fds))
 		for _, fd := range fds {
 			if colName, ok := fieldMap[fd.Name]; ok {
-				scanTargets = append(scanTargets, e.Elem().FieldByName(colName).Addr().Interface())
+				scanTargets = append(scanTargets, dest.Elem().FieldByName(colName).Addr().Interface())
 			}
 		}
-		if err := rows.Scan(scanTargets...); err != nil {
-			return err
-		}
-		v.Set(reflect.Append(v, e))
+		return rows.Scan(scanTargets...)
 	}
-
-	return nil
 }
@@ -1,12 +1,15 @@
 package korm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/netip"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 )
 
@@ -174,3 +177,197 @@ func BenchmarkQueryEmbed(b *testing.B) {
 	}))
 	b.Log(len(result))
 }
+
+func TestQueryWithoutTx(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(Student{}))
+
+	insertAndSelect := func(q Querier, id int) ([]*Student, error) {
+		if _, err := q.Exec("DELETE FROM student WHERE id = $1", id); err != nil {
+			return nil, err
+		}
+		if err := q.Insert(&Student{Id: id, CreateAt: time.Now(), Name: fmt.Sprintf("name%d", id)}); err != nil {
+			return nil, err
+		}
+		var result []*Student
+		err := q.Select(&result, "SELECT * FROM student WHERE id = $1", id)
+		return result, err
+	}
+
+	result, err := insertAndSelect(db, -2)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	require.NoError(t, WithTx(db, func(tx Transaction) error {
+		result, err = insertAndSelect(tx, -3)
+		return err
+	}))
+	require.Len(t, result, 1)
+}
+
+type fakeQuerier struct {
+	Querier
+	rows int
+}
+
+func (q fakeQuerier) SelectCtx(_ context.Context, target any, _ string, _ ...any) error {
+	result := target.(*[]*Student)
+	for i := 0; i < q.rows; i++ {
+		*result = append(*result, &Student{Id: i})
+	}
+	return nil
+}
+
+func (q fakeQuerier) GetCtx(_ context.Context, dest any, _ string, _ ...any) error {
+	switch q.rows {
+	case 0:
+		return ErrNotFound
+	case 1:
+		*dest.(*Student) = Student{Id: 0}
+		return nil
+	default:
+		return ErrMultipleRows
+	}
+}
+
+func (q fakeQuerier) FirstCtx(_ context.Context, dest any, _ string, _ ...any) error {
+	if q.rows == 0 {
+		return ErrNotFound
+	}
+	*dest.(*Student) = Student{Id: 0}
+	return nil
+}
+
+// countingRows yields n rows with a single id column and counts how many of
+// them were read.
+type countingRows struct {
+	pgx.Rows
+	n, read int
+}
+
+func (r *countingRows) Next() bool {
+	if r.read == r.n {
+		return false
+	}
+	r.read++
+	return true
+}
+
+func (r *countingRows) FieldDescriptions() []pgconn.FieldDescription {
+	return []pgconn.FieldDescription{{Name: "id"}}
+}
+
+func (r *countingRows) Scan(dest ...any) error {
+	*dest[0].(*int) = r.read
+	return nil
+}
+
+func (r *countingRows) Close() {}
+
+func (r *countingRows) Err() error {
+	return nil
+}
+
+func (r *countingRows) CommandTag() pgconn.CommandTag {
+	return pgconn.NewCommandTag(fmt.Sprintf("SELECT %d", r.read))
+}
+
+type countingConn struct {
+	dbConn
+	rows *countingRows
+}
+
+func (c countingConn) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return c.rows, nil
+}
+
+func TestGetStopsEarly(t *testing.T) {
+	ctx := context.Background()
+	db := initDB(nil)
+
+	rows := &countingRows{n: 1000}
+	var student Student
+	require.ErrorIs(t, getRow(ctx, db, countingConn{rows: rows}, &student, "SELECT id FROM student"), ErrMultipleRows)
+	require.Equal(t, 2, rows.read)
+	require.Equal(t, 1, student.Id)
+
+	rows = &countingRows{n: 1000}
+	student = Student{}
+	require.NoError(t, firstRow(ctx, db, countingConn{rows: rows}, &student, "SELECT id FROM student"))
+	require.Equal(t, 1, rows.read)
+	require.Equal(t, 1, student.Id)
+
+	rows = &countingRows{n: 1}
+	require.NoError(t, getRow(ctx, db, countingConn{rows: rows}, &student, "SELECT id FROM student"))
+	require.ErrorIs(t, firstRow(ctx, db, countingConn{rows: &countingRows{}}, &student, "SELECT id FROM student"), ErrNotFound)
+
+	// db tags that are not korm options are ignored when scanning.
+	var dto struct {
+		Id   int
+		Name string `db:"user_name"`
+	}
+	require.NoError(t, firstRow(ctx, db, countingConn{rows: &countingRows{n: 1}}, &dto, "SELECT id FROM student"))
+	require.Equal(t, 1, dto.Id)
+}
+
+func TestGenericSelect(t *testing.T) {
+	result, err := Select[Student](fakeQuerier{rows: 2}, "SELECT * FROM student")
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	_, err = Get[Student](fakeQuerier{}, "SELECT * FROM student WHERE id = $1", 1)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = Get[Student](fakeQuerier{rows: 2}, "SELECT * FROM student")
+	require.ErrorIs(t, err, ErrMultipleRows)
+	student, err := Get[Student](fakeQuerier{rows: 1}, "SELECT * FROM student WHERE id = $1", 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, student.Id)
+
+	_, err = First[Student](fakeQuerier{}, "SELECT * FROM student")
+	require.ErrorIs(t, err, ErrNotFound)
+	student, err = First[Student](fakeQuerier{rows: 3}, "SELECT * FROM student ORDER BY id")
+	require.NoError(t, err)
+	require.Equal(t, 0, student.Id)
+}
+
+func TestGet(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(Student{}))
+
+	_, err = db.Exec("DELETE FROM student WHERE id IN (-20, -21)")
+	require.NoError(t, err)
+	require.NoError(t, db.Insert([]*Student{
+		{Id: -20, CreateAt: time.Now(), Name: "get"},
+		{Id: -21, CreateAt: time.Now(), Name: "get"},
+	}))
+
+	var student Student
+	require.NoError(t, db.Get(&student, "SELECT * FROM student WHERE id = $1", -20))
+	require.Equal(t, -20, student.Id)
+	require.ErrorIs(t, db.Get(&student, "SELECT * FROM student WHERE id = $1", -22), ErrNotFound)
+	require.ErrorIs(t, db.Get(&student, "SELECT * FROM student WHERE name = $1", "get"), ErrMultipleRows)
+}
+
+func TestFindByPK(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(IndexModel{}))
+
+	_, err = db.Exec("DELETE FROM index_model WHERE id = $1", -1)
+	require.NoError(t, err)
+	require.NoError(t, db.Insert(&IndexModel{Id: -1, CreateAt: time.Now(), Name: "pk", IdentityCard: "card-pk",
+		JsonColumn: json.RawMessage(`{}`), JsonMap: json.RawMessage(`{}`), Address: netip.MustParseAddr("10.0.0.1")}))
+
+	var model IndexModel
+	require.NoError(t, db.FindByPK(&model, int64(-1)))
+	require.Equal(t, "pk", model.Name)
+	require.ErrorIs(t, db.FindByPK(&model, int64(-2)), ErrNotFound)
+}
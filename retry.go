@@ -0,0 +1,88 @@
+package korm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RetryPolicy controls how WithTxRetry re-runs a failed transaction.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the random delay between attempts,
+	// which doubles with every retry.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// RetryCodes lists the SQLSTATE codes that are worth retrying.
+	RetryCodes []string
+}
+
+// DefaultRetryPolicy retries serialization failures (40001) and deadlocks
+// (40P01) up to three times.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  10 * time.Millisecond,
+	MaxBackoff:  time.Second,
+	RetryCodes:  []string{"40001", "40P01"},
+}
+
+// WithTxRetry runs f in a transaction started with opts and runs it again in
+// a new transaction when it fails with one of policy.RetryCodes. Other errors
+// are returned right away.
+func WithTxRetry(ctx context.Context, d Driver, opts TxOptions, policy RetryPolicy, f func(tx Transaction) error) error {
+	return policy.retry(ctx, func() error {
+		return WithTxOptions(ctx, d, opts, f)
+	})
+}
+
+func (p RetryPolicy) retry(ctx context.Context, f func() error) error {
+	var err error
+	attempt := 0
+	for {
+		attempt++
+		if err = f(); err == nil || !p.retryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return slices.Contains(p.RetryCodes, pgErr.Code)
+}
+
+// backoff returns a random delay in [MinBackoff, MinBackoff*2^(attempt-1)],
+// capped at MaxBackoff.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	upper := p.MinBackoff
+	for i := 1; i < attempt && upper < p.MaxBackoff; i++ {
+		upper *= 2
+	}
+	if upper > p.MaxBackoff {
+		upper = p.MaxBackoff
+	}
+	if upper <= p.MinBackoff {
+		return p.MinBackoff
+	}
+	return p.MinBackoff + rand.N(upper-p.MinBackoff)
+}
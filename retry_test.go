@@ -0,0 +1,85 @@
+package korm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_retry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond,
+		RetryCodes: DefaultRetryPolicy.RetryCodes}
+	serializationErr := fmt.Errorf("insert failed: %w", &pgconn.PgError{Code: "40001"})
+
+	var datas = []struct {
+		name          string
+		errs          []error
+		expectAttempt int
+		expectErr     string
+	}{
+		{
+			name:          "success",
+			errs:          []error{nil},
+			expectAttempt: 1,
+		},
+		{
+			name:          "retry-then-success",
+			errs:          []error{serializationErr, &pgconn.PgError{Code: "40P01"}, nil},
+			expectAttempt: 3,
+		},
+		{
+			name:          "non-retryable",
+			errs:          []error{&pgconn.PgError{Code: "23505"}},
+			expectAttempt: 1,
+			expectErr:     ":  (SQLSTATE 23505)",
+		},
+		{
+			name:          "exhausted",
+			errs:          []error{serializationErr, serializationErr, serializationErr},
+			expectAttempt: 3,
+			expectErr:     "transaction failed after 3 attempts: insert failed: :  (SQLSTATE 40001)",
+		},
+	}
+
+	for _, data := range datas {
+		t.Run(data.name, func(t *testing.T) {
+			attempt := 0
+			err := policy.retry(context.Background(), func() error {
+				attempt++
+				return data.errs[attempt-1]
+			})
+			require.Equal(t, data.expectAttempt, attempt)
+			if data.expectErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, data.expectErr)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_retryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Hour, MaxBackoff: time.Hour,
+		RetryCodes: DefaultRetryPolicy.RetryCodes}
+	err := policy.retry(ctx, func() error {
+		return &pgconn.PgError{Code: "40001"}
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for attempt := 1; attempt < 10; attempt++ {
+		d := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, d, policy.MinBackoff)
+		assert.LessOrEqual(t, d, policy.MaxBackoff)
+	}
+}
@@ -7,55 +7,279 @@ import (
 	"net"
 	"net/netip"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 )
 
+// RegisterModels creates the tables of models and registers them. Tables
+// are created after the tables their foreign keys reference.
 func (s *DB) RegisterModels(models ...any) error {
+	schemas, err := s.buildTableSchemas(models)
+	if err != nil {
+		return err
+	}
+	for _, schema := range schemas {
+		s.setTableCache(schema.field)
+		if err := s.execDDL(context.Background(), schema.field.TableName, schema.sqlList()); err != nil {
+			return fmt.Errorf("register table %s failed: %w", schema.modelName, err)
+		}
+	}
+	for _, schema := range schemas {
+		if err := s.execDDL(context.Background(), schema.field.TableName, schema.foreignKeySql(nil)); err != nil {
+			return fmt.Errorf("register table %s failed: %w", schema.modelName, err)
+		}
+	}
+	return nil
+}
+
+// RegisterModelsNoDDL registers models for Insert, Update and the other
+// model based methods without creating their tables, for schemas managed
+// elsewhere. Use VerifyModels to check that the tables match.
+func (s *DB) RegisterModelsNoDDL(models ...any) error {
 	for _, model := range models {
-		sqlList, err := s.genCreateTableSql(model)
+		schema, err := s.buildTableSchema(model)
 		if err != nil {
 			return err
 		}
+		s.setTableCache(schema.field)
+	}
+	return nil
+}
 
-		for _, sql := range sqlList {
-			fmt.Printf("create table sql:%s\n", sql)
-			if _, err := s.Conn.Exec(context.Background(), sql); err != nil {
-				return fmt.Errorf("register table %s failed: %w", reflect.TypeOf(model).Name(), err)
-			}
+// execDDL runs sqlList in order, tracing each statement as OpDDL.
+func (s *DB) execDDL(ctx context.Context, tableName string, sqlList []string) error {
+	for _, sql := range sqlList {
+		event := &QueryEvent{Op: OpDDL, Table: tableName, SQL: sql}
+		_, err := traceQuery(ctx, s.Tracer, event, func(ctx context.Context) (int64, error) {
+			_, err := s.conn.Exec(ctx, sql)
+			return 0, err
+		})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 func (s *DB) genCreateTableSql(model any) ([]string, error) {
+	schema, err := s.buildTableSchema(model)
+	if err != nil {
+		return nil, err
+	}
+	s.setTableCache(schema.field)
+	return schema.sqlList(), nil
+}
+
+// GenerateSchema returns the DDL RegisterModels would run for models, in
+// order, using the default naming pattern. It does not need a database.
+func GenerateSchema(models ...any) ([]string, error) {
+	return initDB(nil).GenerateSchema(models...)
+}
+
+// SchemaSQL is like GenerateSchema but returns the DDL as one script.
+func SchemaSQL(models ...any) (string, error) {
+	return initDB(nil).SchemaSQL(models...)
+}
+
+// GenerateSchema returns the DDL RegisterModels would run for models, in
+// order, without executing it or registering the models.
+func (s *DB) GenerateSchema(models ...any) ([]string, error) {
+	schemas, err := s.buildTableSchemas(models)
+	if err != nil {
+		return nil, err
+	}
+	var sqlList []string
+	for _, schema := range schemas {
+		sqlList = append(sqlList, schema.sqlList()...)
+	}
+	for _, schema := range schemas {
+		sqlList = append(sqlList, schema.foreignKeySql(nil)...)
+	}
+	return sqlList, nil
+}
+
+// SchemaSQL is like GenerateSchema but returns the DDL as one script, with
+// statements separated by blank lines.
+func (s *DB) SchemaSQL(models ...any) (string, error) {
+	sqlList, err := s.GenerateSchema(models...)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(sqlList, "\n\n") + "\n", nil
+}
+
+// tableSchema is the table layout and DDL generated from a model.
+type tableSchema struct {
+	modelName      string
+	field          *Field
+	columns        []columnDef
+	references     []string
+	createTableSql string
+	createIdxSql   []string
+}
+
+// sqlList returns the statements that create the table and its indexes.
+// Foreign keys that close a cycle are left to foreignKeySql.
+func (ts *tableSchema) sqlList() []string {
+	return append([]string{ts.createTableSql}, ts.createIdxSql...)
+}
+
+// foreignKeySql returns the statements that add the foreign keys left out of
+// CREATE TABLE because they close a cycle, for the columns missing from live.
+// They must run once every table of the cycle exists.
+func (ts *tableSchema) foreignKeySql(live map[string]string) []string {
+	var sqlList []string
+	tableName := ts.field.TableName
+	for _, column := range ts.columns {
+		if _, ok := live[column.name]; ok || !column.deferFK {
+			continue
+		}
+		// Name the constraint the way Postgres names inline ones, and ignore
+		// it when it already exists so that the statement can be rerun.
+		sqlList = append(sqlList, fmt.Sprintf("DO $$ BEGIN ALTER TABLE %s ADD CONSTRAINT %s_%s_fkey FOREIGN KEY (%s) %s; "+
+			"EXCEPTION WHEN duplicate_object THEN NULL; END $$;", tableName, tableName, column.name, column.name, column.references))
+	}
+	return sqlList
+}
+
+// buildTableSchemas builds the schema of every model, ordered so that each
+// table comes after the tables its foreign keys reference. References to
+// tables outside models are assumed to exist already. When foreign keys form
+// a cycle, the ones closing it are left out of CREATE TABLE and added later
+// by foreignKeySql.
+func (s *DB) buildTableSchemas(models []any) ([]*tableSchema, error) {
+	byName := make(map[string]*tableSchema, len(models))
+	schemas := make([]*tableSchema, 0, len(models))
+	for _, model := range models {
+		schema, err := s.buildTableSchema(model)
+		if err != nil {
+			return nil, err
+		}
+		byName[schema.field.TableName] = schema
+		schemas = append(schemas, schema)
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(schemas))
+	sorted := make([]*tableSchema, 0, len(schemas))
+	var visit func(schema *tableSchema) error
+	visit = func(schema *tableSchema) error {
+		tableName := schema.field.TableName
+		if state[tableName] != 0 {
+			return nil
+		}
+		state[tableName] = visiting
+		for _, refTable := range schema.references {
+			ref, ok := byName[refTable]
+			if !ok || refTable == tableName {
+				continue
+			}
+			if state[refTable] == visiting {
+				schema.deferForeignKeys(refTable)
+				continue
+			}
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		state[tableName] = visited
+		sorted = append(sorted, schema)
+		return nil
+	}
+	for _, schema := range schemas {
+		if err := visit(schema); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+func (s *DB) buildTableSchema(model any) (*tableSchema, error) {
 	t := reflect.TypeOf(model)
 	if t.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("model must be a struct")
 	}
 	tableName := s.TableName(t.Name())
 	compositeIdxMap := make(map[string][]string)
+	compositeUkMap := make(map[string][]string)
 	unique := make(map[string]struct{})
-	columns, colTypes, createIdxSql, err := s.parseFields(tableName, t, unique, compositeIdxMap)
+	columns, err := s.parseFields(tableName, t, unique, compositeIdxMap, compositeUkMap)
 	if err != nil {
 		return nil, err
 	}
 	field := newField(tableName)
-	field.addColumns(columns)
-	s.tableCache[field.TableName] = field
+	for _, column := range columns {
+		field.addColumn(column)
+	}
+	for _, ukName := range sortedKeys(compositeUkMap) {
+		field.UniqueKeys = append(field.UniqueKeys, compositeUkMap[ukName])
+	}
 
-	colSql := make([]string, len(columns))
-	for i, column := range columns {
-		colSql[i] = fmt.Sprintf("%s %s", column, colTypes[i])
+	createIdxSql := make([]string, 0, len(columns))
+	var references []string
+	for _, column := range columns {
+		if len(column.indexSQL) != 0 {
+			createIdxSql = append(createIdxSql, column.indexSQL)
+		}
+		if len(column.refTable) != 0 && !slices.Contains(references, column.refTable) {
+			references = append(references, column.refTable)
+		}
 	}
-	createTableSql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);",
-		tableName, strings.Join(colSql, ",\n"))
-	for indexName, fields := range compositeIdxMap {
-		indexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);", tableName, indexName, tableName, strings.Join(fields, ", "))
+	for _, indexName := range sortedKeys(compositeIdxMap) {
+		indexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);", tableName, indexName, tableName, strings.Join(compositeIdxMap[indexName], ", "))
 		createIdxSql = append(createIdxSql, indexSQL)
 	}
+	for _, ukName := range sortedKeys(compositeUkMap) {
+		indexSQL := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS uk_%s_%s ON %s (%s);", tableName, ukName, tableName, strings.Join(compositeUkMap[ukName], ", "))
+		createIdxSql = append(createIdxSql, indexSQL)
+	}
+
+	schema := &tableSchema{
+		modelName:    t.Name(),
+		field:        field,
+		columns:      columns,
+		references:   references,
+		createIdxSql: createIdxSql,
+	}
+	schema.buildCreateTableSql()
+	return schema, nil
+}
+
+func (ts *tableSchema) buildCreateTableSql() {
+	inlinePK := len(ts.field.PrimaryKeys) == 1
+	colSql := make([]string, len(ts.columns), len(ts.columns)+1)
+	for i, column := range ts.columns {
+		colSql[i] = column.definition(inlinePK)
+	}
+	if len(ts.field.PrimaryKeys) > 1 {
+		colSql = append(colSql, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(ts.field.PrimaryKeys, ", ")))
+	}
+	ts.createTableSql = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);",
+		ts.field.TableName, strings.Join(colSql, ",\n"))
+}
 
-	return append([]string{createTableSql}, createIdxSql...), nil
+// deferForeignKeys leaves the foreign keys referencing refTable out of
+// CREATE TABLE, to be added by foreignKeySql.
+func (ts *tableSchema) deferForeignKeys(refTable string) {
+	for i := range ts.columns {
+		if ts.columns[i].refTable == refTable {
+			ts.columns[i].deferFK = true
+		}
+	}
+	ts.buildCreateTableSql()
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
 }
 
 func (s *DB) getTableName(model any) (string, error) {
@@ -66,37 +290,55 @@ func (s *DB) getTableName(model any) (string, error) {
 	return s.TableName(t.Name()), nil
 }
 
-func (s *DB) parseFields(tableName string, t reflect.Type, unique map[string]struct{}, compositeIdxMap map[string][]string) (
-	columns []string, columnTypes []string, createIdxSql []string, err error) {
-	columns = make([]string, 0, t.NumField())
-	columnTypes = make([]string, 0, t.NumField())
-	createIdxSql = make([]string, 0, t.NumField())
+// columnDef is a struct field mapped to a table column.
+type columnDef struct {
+	name            string
+	dbType          string
+	pk              bool
+	uk              bool
+	notNull         bool
+	defaultValue    string
+	generated       bool
+	identity        bool
+	indexSQL        string
+	compositeIndex  string
+	compositeUnique string
+	refTable        string
+	references      string
+	// deferFK leaves the foreign key out of the column definition, because
+	// it closes a cycle and is added once every table exists.
+	deferFK bool
+}
+
+func (s *DB) parseFields(tableName string, t reflect.Type, unique map[string]struct{}, compositeIdxMap map[string][]string, compositeUkMap map[string][]string) (
+	columns []columnDef, err error) {
+	columns = make([]columnDef, 0, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
+		if _, err := parseTag(field.Tag.Get("db")); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
 		if isFieldEmbed(field) {
-			col, colTypes, idxSql, err := s.parseFields(tableName, field.Type, unique, compositeIdxMap)
+			cols, err := s.parseFields(tableName, field.Type, unique, compositeIdxMap, compositeUkMap)
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, err
 			} else {
-				columns = append(columns, col...)
-				columnTypes = append(columnTypes, colTypes...)
-				createIdxSql = append(createIdxSql, idxSql...)
+				columns = append(columns, cols...)
 			}
 		} else {
-			col, colTypes, indexSQL, compositeIndex, err := s.genColumnSql(tableName, field)
+			col, err := s.genColumnSql(tableName, field)
 			if err != nil {
-				return nil, nil, nil, err
+				return nil, err
 			}
-			if len(col) == 0 {
+			if len(col.name) == 0 {
 				continue
 			}
 			columns = append(columns, col)
-			columnTypes = append(columnTypes, colTypes)
-			if len(indexSQL) != 0 {
-				createIdxSql = append(createIdxSql, indexSQL)
+			if len(col.compositeIndex) != 0 {
+				compositeIdxMap[col.compositeIndex] = append(compositeIdxMap[col.compositeIndex], col.name)
 			}
-			if len(compositeIndex) != 0 {
-				compositeIdxMap[compositeIndex] = append(compositeIdxMap[compositeIndex], s.ColumnName(field.Name))
+			if len(col.compositeUnique) != 0 {
+				compositeUkMap[col.compositeUnique] = append(compositeUkMap[col.compositeUnique], col.name)
 			}
 		}
 
@@ -104,68 +346,210 @@ func (s *DB) parseFields(tableName string, t reflect.Type, unique map[string]str
 		if _, ok := unique[columnName]; !ok {
 			unique[columnName] = struct{}{}
 		} else {
-			return nil, nil, nil, fmt.Errorf("column %s already exists", columnName)
+			return nil, fmt.Errorf("column %s already exists", columnName)
 		}
 	}
-	return columns, columnTypes, createIdxSql, nil
+	return columns, nil
 }
 
 func isFieldEmbed(field reflect.StructField) bool {
 	if field.Anonymous {
 		return true
 	}
-	if tag := field.Tag.Get("db"); strings.Contains(tag, "embed") {
-		return true
-	}
-	return false
+	// Tags are validated when the model is registered, so errors are ignored.
+	tag, _ := parseTag(field.Tag.Get("db"))
+	_, ok := tag["embed"]
+	return ok
 }
 
-func (s *DB) genColumnSql(tableName string, field reflect.StructField) (col string, colType string, indexSQL string, compositeIndex string, err error) {
-	name := s.DBPattern.ColumnName(field.Name)
-	tag := field.Tag.Get("db")
-	var ukIndex string
-	if len(tag) > 0 {
-		if strings.Contains(tag, "-") {
-			return
+// tagOptions lists the options accepted in a db tag.
+var tagOptions = map[string]struct{}{
+	"-": {}, "pk": {}, "uk": {}, "notNull": {}, "default": {}, "generated": {},
+	"autoIncrement": {}, "identity": {}, "index": {}, "embed": {},
+	"fk": {}, "onDelete": {}, "onUpdate": {},
+}
+
+// parseTag splits a db tag such as "pk,index=name" into its options, mapping
+// each option name to its value, if any. Commas inside parentheses, brackets
+// or quotes, as in "default=ARRAY[1,2]", do not end an option. Unknown
+// options and index or unique key names that are not identifiers are
+// errors.
+func parseTag(tag string) (map[string]string, error) {
+	options := make(map[string]string)
+	for _, option := range splitTag(tag) {
+		option = strings.TrimSpace(option)
+		if len(option) == 0 {
+			continue
+		}
+		key, value, _ := strings.Cut(option, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if _, ok := tagOptions[key]; !ok {
+			return nil, fmt.Errorf("unknown db tag option %q in %q", key, tag)
 		}
-		if strings.Contains(tag, "pk") {
-			ukIndex += " PRIMARY KEY"
+		if (key == "index" || key == "uk") && len(value) > 0 && !isIdentifier(value) {
+			return nil, fmt.Errorf("%s name %q in db tag %q must be an identifier", key, value, tag)
 		}
-		if strings.Contains(tag, "uk") {
-			ukIndex += " UNIQUE"
+		options[key] = value
+	}
+	return options, nil
+}
+
+// splitTag splits tag on the commas that are not inside parentheses,
+// brackets or single quotes.
+func splitTag(tag string) []string {
+	var options []string
+	depth, quoted, start := 0, false, 0
+	for i, r := range tag {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			depth--
+		case r == ',' && depth <= 0:
+			options = append(options, tag[start:i])
+			start = i + 1
 		}
-		if strings.Contains(tag, "notNull") {
-			ukIndex += " NOT NULL"
+	}
+	return append(options, tag[start:])
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && (i == 0 || !('0' <= r && r <= '9')) {
+			return false
 		}
-		if strings.Contains(tag, "index") {
-			if strings.Contains(tag, "index=") {
-				compositeIndex = extractIndexName(tag)
-			} else {
-				indexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);", tableName, name, tableName, name)
-			}
+	}
+	return len(name) > 0
+}
+
+func (s *DB) genColumnSql(tableName string, field reflect.StructField) (col columnDef, err error) {
+	name := s.DBPattern.ColumnName(field.Name)
+	tag, err := parseTag(field.Tag.Get("db"))
+	if err != nil {
+		return columnDef{}, fmt.Errorf("field %s: %w", field.Name, err)
+	}
+	if _, ok := tag["-"]; ok {
+		return
+	}
+	_, col.pk = tag["pk"]
+	if uk, ok := tag["uk"]; ok {
+		if len(uk) > 0 {
+			col.compositeUnique = uk
+		} else {
+			col.uk = true
+		}
+	}
+	_, col.notNull = tag["notNull"]
+	col.defaultValue = tag["default"]
+	_, col.generated = tag["generated"]
+	_, autoIncrement := tag["autoIncrement"]
+	_, identity := tag["identity"]
+	col.identity = autoIncrement || identity
+	if index, ok := tag["index"]; ok {
+		if len(index) > 0 {
+			col.compositeIndex = index
+		} else {
+			col.indexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);", tableName, name, tableName, name)
 		}
 	}
 
+	if col.references, col.refTable, err = foreignKeyClause(name, tag); err != nil {
+		return columnDef{}, err
+	}
+
 	tpy := field.Type
 	dbType, err := goTypeToPostgresType(tpy)
 	if err != nil {
-		return "", "", "", "", err
+		return columnDef{}, err
+	}
+
+	if col.identity {
+		switch dbType {
+		case "SMALLINT", "INTEGER", "BIGINT":
+		default:
+			return columnDef{}, fmt.Errorf("identity column %s must be an integer, got %s", name, dbType)
+		}
 	}
 
-	if dbType == "JSONB" && indexSQL != "" {
-		indexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s USING GIN (%s);", tableName, name, tableName, name)
+	if dbType == "JSONB" && col.indexSQL != "" {
+		col.indexSQL = fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s USING GIN (%s);", tableName, name, tableName, name)
 	}
-	col = name
-	colType = dbType + ukIndex
+	col.name = name
+	col.dbType = dbType
 	return
 }
 
-func extractIndexName(tags string) string {
-	parts := strings.Split(tags, "index=")
-	if len(parts) > 1 {
-		return strings.TrimSpace(parts[1])
+// definition returns the column definition used in CREATE TABLE. The PRIMARY
+// KEY constraint is only inlined for single-column primary keys.
+func (c columnDef) definition(inlinePK bool) string {
+	def := c.name + " " + c.dbType
+	if c.identity {
+		def += " GENERATED BY DEFAULT AS IDENTITY"
+	}
+	if c.pk && inlinePK {
+		def += " PRIMARY KEY"
+	}
+	if c.uk {
+		def += " UNIQUE"
+	}
+	if c.notNull {
+		def += " NOT NULL"
+	}
+	if len(c.defaultValue) > 0 {
+		def += " DEFAULT " + c.defaultValue
+	}
+	if len(c.references) > 0 && !c.deferFK {
+		def += " " + c.references
+	}
+	return def
+}
+
+// foreignKeyActions maps the onDelete and onUpdate tag values, compared
+// case-insensitively, to referential actions.
+var foreignKeyActions = map[string]string{
+	"cascade":    "CASCADE",
+	"restrict":   "RESTRICT",
+	"noaction":   "NO ACTION",
+	"setnull":    "SET NULL",
+	"setdefault": "SET DEFAULT",
+}
+
+// foreignKeyClause returns the REFERENCES clause for a column tagged
+// fk=table.column, or fk=table to reference its primary key, and the
+// referenced table.
+func foreignKeyClause(name string, tag map[string]string) (clause string, refTable string, err error) {
+	fk, ok := tag["fk"]
+	if !ok {
+		for _, key := range []string{"onDelete", "onUpdate"} {
+			if _, ok := tag[key]; ok {
+				return "", "", fmt.Errorf("column %s has %s but no fk", name, key)
+			}
+		}
+		return "", "", nil
+	}
+	refTable, refColumn, _ := strings.Cut(fk, ".")
+	if len(refTable) == 0 {
+		return "", "", fmt.Errorf("foreign key of column %s must name a table", name)
+	}
+	clause = "REFERENCES " + refTable
+	if len(refColumn) > 0 {
+		clause += " (" + refColumn + ")"
+	}
+	for _, event := range []struct{ key, sql string }{{"onDelete", "ON DELETE"}, {"onUpdate", "ON UPDATE"}} {
+		value, ok := tag[event.key]
+		if !ok {
+			continue
+		}
+		action, ok := foreignKeyActions[strings.ToLower(strings.ReplaceAll(value, " ", ""))]
+		if !ok {
+			return "", "", fmt.Errorf("unknown %s action %s on column %s", event.key, value, name)
+		}
+		clause += " " + event.sql + " " + action
 	}
-	return ""
+	return clause, refTable, nil
 }
 
 func goTypeToPostgresType(goType reflect.Type) (string, error) {
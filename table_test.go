@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net/netip"
+	"strings"
 	"testing"
 	"time"
 )
@@ -52,6 +53,34 @@ type Child struct {
 	Email   string   `json:"email"`
 }
 
+type GeneratedModel struct {
+	Id       int64     `db:"pk"`
+	Name     string    `db:"uk,notNull"`
+	CreateAt time.Time `db:"generated,default=now()"`
+}
+
+type IdentityModel struct {
+	Id   int64 `db:"pk,autoIncrement"`
+	Name string
+}
+
+type BadIdentityModel struct {
+	Id string `db:"identity"`
+}
+
+type CompositeModel struct {
+	TenantId int64 `db:"pk"`
+	UserId   int64 `db:"pk"`
+	Name     string
+}
+
+type TenantUserModel struct {
+	Id       int64  `db:"pk"`
+	TenantId int64  `db:"uk=tenant_email"`
+	Email    string `db:"uk=tenant_email"`
+	Phone    string `db:"uk"`
+}
+
 type DuplicateModel struct {
 	Id       int64     `db:"pk"`
 	CreateAt time.Time `db:"notNull"`
@@ -61,6 +90,30 @@ type DuplicateModel struct {
 	Child
 }
 
+type FkUser struct {
+	Id int64 `db:"pk"`
+}
+
+type FkPost struct {
+	Id       int64 `db:"pk"`
+	FkUserId int64 `db:"fk=fk_user.id,onDelete=cascade,onUpdate=noAction,index"`
+	ParentId int64 `db:"fk=fk_post"`
+}
+
+type FkCycleA struct {
+	Id       int64 `db:"pk"`
+	FkCycleB int64 `db:"fk=fk_cycle_b.id"`
+}
+
+type FkCycleB struct {
+	Id       int64 `db:"pk"`
+	FkCycleA int64 `db:"fk=fk_cycle_a.id"`
+}
+
+type BadFkActionModel struct {
+	UserId int64 `db:"fk=fk_user.id,onDelete=drop"`
+}
+
 func TestDB_genCreateTableSql(t *testing.T) {
 	var datas = []struct {
 		name          string
@@ -118,6 +171,55 @@ email TEXT
 				`CREATE INDEX IF NOT EXISTS idx_embed_model_name_alias ON embed_model (name, alias);`,
 			},
 		},
+		{
+			name:      "generated-table",
+			model:     GeneratedModel{},
+			expectErr: nil,
+			expectSqlList: []string{`CREATE TABLE IF NOT EXISTS generated_model (
+id BIGINT PRIMARY KEY,
+name TEXT UNIQUE NOT NULL,
+create_at TIMESTAMP DEFAULT now()
+);`},
+		},
+		{
+			name:      "identity-table",
+			model:     IdentityModel{},
+			expectErr: nil,
+			expectSqlList: []string{`CREATE TABLE IF NOT EXISTS identity_model (
+id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+name TEXT
+);`},
+		},
+		{
+			name:          "bad-identity-table",
+			model:         BadIdentityModel{},
+			expectErr:     fmt.Errorf("identity column id must be an integer, got TEXT"),
+			expectSqlList: nil,
+		},
+		{
+			name:      "composite-pk-table",
+			model:     CompositeModel{},
+			expectErr: nil,
+			expectSqlList: []string{`CREATE TABLE IF NOT EXISTS composite_model (
+tenant_id BIGINT,
+user_id BIGINT,
+name TEXT,
+PRIMARY KEY (tenant_id, user_id)
+);`},
+		},
+		{
+			name:      "composite-uk-table",
+			model:     TenantUserModel{},
+			expectErr: nil,
+			expectSqlList: []string{`CREATE TABLE IF NOT EXISTS tenant_user_model (
+id BIGINT PRIMARY KEY,
+tenant_id BIGINT,
+email TEXT,
+phone TEXT UNIQUE
+);`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS uk_tenant_user_model_tenant_email ON tenant_user_model (tenant_id, email);`,
+			},
+		},
 		{
 			name:          "duplicate-table",
 			model:         DuplicateModel{},
@@ -146,3 +248,128 @@ func TestDB_RegisterModels(t *testing.T) {
 	require.NoError(t, err)
 	assert.NoError(t, db.RegisterModels(Model{}, IndexModel{}, EmbedModel{}))
 }
+
+func TestDB_RegisterModelsNoDDL(t *testing.T) {
+	db := initDB(nil)
+	require.NoError(t, db.RegisterModelsNoDDL(IdentityModel{}, CompositeModel{}))
+	field, ok := db.GetTableCache("composite_model")
+	require.True(t, ok)
+	require.Equal(t, []string{"tenant_id", "user_id"}, field.PrimaryKeys)
+	_, ok = db.GetTableCache("identity_model")
+	require.True(t, ok)
+
+	require.Error(t, db.RegisterModelsNoDDL(BadIdentityModel{}))
+}
+
+func TestDB_genCreateTableSqlPrimaryKeys(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(EmbedModel{})
+	require.NoError(t, err)
+	field, ok := db.GetTableCache("embed_model")
+	require.True(t, ok)
+	require.Equal(t, []string{"id"}, field.PrimaryKeys)
+
+	sql, err := field.selectByPKSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id, create_at, name, alias, age, identity_card, friends, email FROM embed_model WHERE id = $1", sql)
+
+	_, err = db.genCreateTableSql(CompositeModel{})
+	require.NoError(t, err)
+	field, ok = db.GetTableCache("composite_model")
+	require.True(t, ok)
+	require.Equal(t, []string{"tenant_id", "user_id"}, field.PrimaryKeys)
+	sql, err = field.selectByPKSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT tenant_id, user_id, name FROM composite_model WHERE tenant_id = $1 AND user_id = $2", sql)
+
+	_, err = db.genCreateTableSql(Model{})
+	require.NoError(t, err)
+	field, ok = db.GetTableCache("model")
+	require.True(t, ok)
+	_, err = field.selectByPKSql()
+	require.EqualError(t, err, "table model has no primary key")
+}
+
+func TestDB_genCreateTableSqlUniqueKeys(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(TenantUserModel{})
+	require.NoError(t, err)
+	field, ok := db.GetTableCache("tenant_user_model")
+	require.True(t, ok)
+	require.Equal(t, [][]string{{"phone"}, {"tenant_id", "email"}}, field.UniqueKeys)
+
+	sql, err := field.onConflictSql([]string{"tenant_id", "email"}, []string{"phone"})
+	require.NoError(t, err)
+	require.Equal(t, "ON CONFLICT (tenant_id, email) DO UPDATE SET phone = EXCLUDED.phone", sql)
+}
+
+func TestGenerateSchema(t *testing.T) {
+	db := initDB(nil)
+	sqlList, err := db.GenerateSchema(IdentityModel{}, TenantUserModel{})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS identity_model (\nid BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,\nname TEXT\n);",
+		"CREATE TABLE IF NOT EXISTS tenant_user_model (\nid BIGINT PRIMARY KEY,\ntenant_id BIGINT,\nemail TEXT,\nphone TEXT UNIQUE\n);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS uk_tenant_user_model_tenant_email ON tenant_user_model (tenant_id, email);",
+	}, sqlList)
+	_, ok := db.GetTableCache("identity_model")
+	require.False(t, ok)
+
+	script, err := SchemaSQL(IdentityModel{}, TenantUserModel{})
+	require.NoError(t, err)
+	require.Equal(t, strings.Join(sqlList, "\n\n")+"\n", script)
+
+	_, err = GenerateSchema(BadIdentityModel{})
+	require.EqualError(t, err, "identity column id must be an integer, got TEXT")
+}
+
+func TestGenerateSchemaForeignKeys(t *testing.T) {
+	sqlList, err := GenerateSchema(FkPost{}, FkUser{})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS fk_user (\nid BIGINT PRIMARY KEY\n);",
+		"CREATE TABLE IF NOT EXISTS fk_post (\nid BIGINT PRIMARY KEY,\nfk_user_id BIGINT REFERENCES fk_user (id) ON DELETE CASCADE ON UPDATE NO ACTION,\nparent_id BIGINT REFERENCES fk_post\n);",
+		"CREATE INDEX IF NOT EXISTS idx_fk_post_fk_user_id ON fk_post (fk_user_id);",
+	}, sqlList)
+
+	// The foreign key closing the cycle is added once both tables exist.
+	sqlList, err = GenerateSchema(FkCycleA{}, FkCycleB{})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS fk_cycle_b (\nid BIGINT PRIMARY KEY,\nfk_cycle_a BIGINT\n);",
+		"CREATE TABLE IF NOT EXISTS fk_cycle_a (\nid BIGINT PRIMARY KEY,\nfk_cycle_b BIGINT REFERENCES fk_cycle_b (id)\n);",
+		"DO $$ BEGIN ALTER TABLE fk_cycle_b ADD CONSTRAINT fk_cycle_b_fk_cycle_a_fkey FOREIGN KEY (fk_cycle_a) REFERENCES fk_cycle_a (id); " +
+			"EXCEPTION WHEN duplicate_object THEN NULL; END $$;",
+	}, sqlList)
+
+	_, err = GenerateSchema(BadFkActionModel{})
+	require.EqualError(t, err, "unknown onDelete action drop on column user_id")
+}
+
+type BadTagModel struct {
+	Id   int64  `db:"pk notNull"`
+	Name string `db:"index=name-alias"`
+}
+
+func TestParseTag(t *testing.T) {
+	tag, err := parseTag("pk, default=ARRAY[1,2],notNull")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"pk": "", "default": "ARRAY[1,2]", "notNull": ""}, tag)
+
+	tag, err = parseTag("default='a,b',index=name_alias")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"default": "'a,b'", "index": "name_alias"}, tag)
+
+	tag, err = parseTag("default=coalesce(1, 2)")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"default": "coalesce(1, 2)"}, tag)
+
+	_, err = parseTag("pk notNull")
+	require.EqualError(t, err, `unknown db tag option "pk notNull" in "pk notNull"`)
+
+	_, err = parseTag("index=name-alias")
+	require.EqualError(t, err, `index name "name-alias" in db tag "index=name-alias" must be an identifier`)
+
+	_, err = GenerateSchema(BadTagModel{})
+	require.EqualError(t, err, `field Id: unknown db tag option "pk notNull" in "pk notNull"`)
+}
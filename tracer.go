@@ -0,0 +1,82 @@
+package korm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type QueryOp string
+
+const (
+	OpExec   QueryOp = "exec"
+	OpSelect QueryOp = "select"
+	OpCopy   QueryOp = "copy"
+	OpDDL    QueryOp = "ddl"
+)
+
+// QueryEvent describes one statement sent to the database. Duration,
+// RowsAffected and Err are only set when it is passed to AfterQuery.
+type QueryEvent struct {
+	Op           QueryOp
+	Table        string
+	SQL          string
+	Args         []any
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+}
+
+// Tracer observes every statement run through a DB or its transactions.
+// The context returned by BeforeQuery is used to run the statement and is
+// passed on to AfterQuery.
+type Tracer interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+func traceQuery(ctx context.Context, t Tracer, event *QueryEvent, f func(ctx context.Context) (int64, error)) (int64, error) {
+	if t == nil {
+		return f(ctx)
+	}
+
+	ctx = t.BeforeQuery(ctx, event)
+	start := time.Now()
+	event.RowsAffected, event.Err = f(ctx)
+	event.Duration = time.Since(start)
+	t.AfterQuery(ctx, event)
+	return event.RowsAffected, event.Err
+}
+
+// SlogTracer logs every statement to Logger, at debug level on success and
+// at error level on failure. Set RedactArgs to keep query arguments out of
+// the log.
+type SlogTracer struct {
+	Logger     *slog.Logger
+	RedactArgs bool
+}
+
+func (t SlogTracer) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (t SlogTracer) AfterQuery(ctx context.Context, event *QueryEvent) {
+	attrs := []slog.Attr{
+		slog.String("op", string(event.Op)),
+		slog.String("sql", event.SQL),
+		slog.Duration("duration", event.Duration),
+		slog.Int64("rows", event.RowsAffected),
+	}
+	if event.Table != "" {
+		attrs = append(attrs, slog.String("table", event.Table))
+	}
+	if len(event.Args) > 0 && !t.RedactArgs {
+		attrs = append(attrs, slog.Any("args", event.Args))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.Any("error", event.Err))
+		t.Logger.LogAttrs(ctx, slog.LevelError, "query failed", attrs...)
+		return
+	}
+	t.Logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs...)
+}
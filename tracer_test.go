@@ -0,0 +1,62 @@
+package korm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordTracer struct {
+	before []QueryEvent
+	after  []QueryEvent
+}
+
+func (t *recordTracer) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	t.before = append(t.before, *event)
+	return ctx
+}
+
+func (t *recordTracer) AfterQuery(_ context.Context, event *QueryEvent) {
+	t.after = append(t.after, *event)
+}
+
+func TestTraceQuery(t *testing.T) {
+	tracer := &recordTracer{}
+	queryErr := errors.New("query failed")
+	event := &QueryEvent{Op: OpExec, SQL: "DELETE FROM student WHERE id = $1", Args: []any{1}}
+	rows, err := traceQuery(context.Background(), tracer, event, func(context.Context) (int64, error) {
+		return 3, queryErr
+	})
+	require.Equal(t, queryErr, err)
+	require.Equal(t, int64(3), rows)
+	require.Len(t, tracer.before, 1)
+	require.Len(t, tracer.after, 1)
+	assert.Equal(t, "DELETE FROM student WHERE id = $1", tracer.before[0].SQL)
+	assert.Nil(t, tracer.before[0].Err)
+	assert.Equal(t, int64(3), tracer.after[0].RowsAffected)
+	assert.Equal(t, queryErr, tracer.after[0].Err)
+}
+
+func TestSlogTracer(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	event := &QueryEvent{Op: OpSelect, SQL: "SELECT * FROM student WHERE name = $1", Args: []any{"secret"}}
+
+	SlogTracer{Logger: logger}.AfterQuery(context.Background(), event)
+	assert.Contains(t, buf.String(), "secret")
+
+	buf.Reset()
+	SlogTracer{Logger: logger, RedactArgs: true}.AfterQuery(context.Background(), event)
+	assert.Contains(t, buf.String(), "level=DEBUG")
+	assert.NotContains(t, buf.String(), "secret")
+
+	buf.Reset()
+	event.Err = errors.New("syntax error")
+	SlogTracer{Logger: logger}.AfterQuery(context.Background(), event)
+	assert.Contains(t, buf.String(), "level=ERROR")
+}
@@ -0,0 +1,176 @@
+package korm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+func (s *DB) Update(model any) (int64, error) {
+	return s.UpdateCtx(context.Background(), model)
+}
+
+func (s *DB) UpdateCtx(ctx context.Context, model any) (int64, error) {
+	return update(ctx, s, s.conn, model)
+}
+
+func (tx DBTx) Update(model any) (int64, error) {
+	return tx.UpdateCtx(context.Background(), model)
+}
+
+func (tx DBTx) UpdateCtx(ctx context.Context, model any) (int64, error) {
+	return update(ctx, tx.Driver, tx.Tx, model)
+}
+
+func (s *DB) UpdateColumns(model any, columns ...string) (int64, error) {
+	return s.UpdateColumnsCtx(context.Background(), model, columns...)
+}
+
+func (s *DB) UpdateColumnsCtx(ctx context.Context, model any, columns ...string) (int64, error) {
+	return updateColumns(ctx, s, s.conn, model, columns)
+}
+
+func (tx DBTx) UpdateColumns(model any, columns ...string) (int64, error) {
+	return tx.UpdateColumnsCtx(context.Background(), model, columns...)
+}
+
+func (tx DBTx) UpdateColumnsCtx(ctx context.Context, model any, columns ...string) (int64, error) {
+	return updateColumns(ctx, tx.Driver, tx.Tx, model, columns)
+}
+
+func (s *DB) UpdateFields(model any, fields map[string]any) (int64, error) {
+	return s.UpdateFieldsCtx(context.Background(), model, fields)
+}
+
+func (s *DB) UpdateFieldsCtx(ctx context.Context, model any, fields map[string]any) (int64, error) {
+	return updateFields(ctx, s, s.conn, model, fields)
+}
+
+func (tx DBTx) UpdateFields(model any, fields map[string]any) (int64, error) {
+	return tx.UpdateFieldsCtx(context.Background(), model, fields)
+}
+
+func (tx DBTx) UpdateFieldsCtx(ctx context.Context, model any, fields map[string]any) (int64, error) {
+	return updateFields(ctx, tx.Driver, tx.Tx, model, fields)
+}
+
+// update writes every mapped column of model, a struct pointer or a slice of
+// them, to the row with the same primary key.
+func update(ctx context.Context, d Driver, conn dbConn, model any) (int64, error) {
+	return updateEach(ctx, d, conn, model, nil, nil)
+}
+
+// updateColumns is like update but only writes the given columns.
+func updateColumns(ctx context.Context, d Driver, conn dbConn, model any, columns []string) (int64, error) {
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("no columns to update")
+	}
+	return updateEach(ctx, d, conn, model, columns, nil)
+}
+
+// updateFields sets the columns in fields to the given values on the rows
+// identified by model's primary key.
+func updateFields(ctx context.Context, d Driver, conn dbConn, model any, fields map[string]any) (int64, error) {
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("no columns to update")
+	}
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = fields[column]
+	}
+	return updateEach(ctx, d, conn, model, columns, values)
+}
+
+// updateEach runs one UPDATE per element of model, setting columns (all
+// non-key columns when nil) to values, or to the element's own values when
+// values is nil. Column names are checked against the registered Field before
+// any statement is sent.
+func updateEach(ctx context.Context, d Driver, conn dbConn, model any, columns []string, values []any) (int64, error) {
+	t, elems, err := modelValues(model)
+	if err != nil {
+		return 0, err
+	}
+	field, err := lookupField(d, t)
+	if err != nil {
+		return 0, err
+	}
+	if columns == nil {
+		columns = field.updatableColumns()
+	}
+	query, err := field.updateSql(columns)
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	for _, elem := range elems {
+		row, err := buildInsertRow(d, field, t, elem)
+		if err != nil {
+			return affected, err
+		}
+		args := values
+		if args == nil {
+			args = field.columnValues(row, columns)
+		}
+		args = append(args[:len(args):len(args)], field.columnValues(row, field.PrimaryKeys)...)
+		n, err := exec(ctx, d, conn, query, args...)
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
+
+// modelValues returns the struct type behind model, which must be a struct
+// pointer or a slice of struct pointers, and the pointers it holds.
+func modelValues(model any) (reflect.Type, []reflect.Value, error) {
+	v := reflect.ValueOf(model)
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.Elem().Kind() != reflect.Struct {
+			return nil, nil, fmt.Errorf("model must be a pointer to a struct")
+		}
+		return v.Type().Elem(), []reflect.Value{v}, nil
+	case reflect.Slice:
+		t := v.Type().Elem()
+		if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			return nil, nil, fmt.Errorf("model element must be a pointer to a struct")
+		}
+		elems := make([]reflect.Value, v.Len())
+		for i := range elems {
+			elems[i] = v.Index(i)
+		}
+		return t.Elem(), elems, nil
+	default:
+		return nil, nil, fmt.Errorf("model must be a slice or pointer")
+	}
+}
+
+func (f *Field) updateSql(columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "", fmt.Errorf("table %s has no columns to update", f.TableName)
+	}
+	setCols := make([]string, len(columns))
+	for i, column := range columns {
+		if _, ok := f.ColumnMap[column]; !ok {
+			return "", fmt.Errorf("column %s not found in table %s", column, f.TableName)
+		}
+		if f.isPrimaryKey(column) {
+			return "", fmt.Errorf("primary key column %s cannot be updated", column)
+		}
+		setCols[i] = fmt.Sprintf("%s = $%d", column, i+1)
+	}
+	cond, err := f.pkCondition(len(setCols) + 1)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", f.TableName, strings.Join(setCols, ", "), cond), nil
+}
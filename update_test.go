@@ -0,0 +1,142 @@
+package korm
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type SkipChild struct {
+	Note  string `db:"-"`
+	Email string
+}
+
+type SkipEmbedModel struct {
+	Id int64 `db:"pk"`
+	SkipChild
+	Name string
+}
+
+type UnexportedFieldModel struct {
+	Id   int64 `db:"pk"`
+	Name string
+	mu   sync.Mutex `db:"-"`
+}
+
+func TestField_updateSql(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(EmbedModel{})
+	require.NoError(t, err)
+	field, _ := db.GetTableCache("embed_model")
+
+	sql, err := field.updateSql(field.updatableColumns())
+	require.NoError(t, err)
+	require.Equal(t, "UPDATE embed_model SET create_at = $1, name = $2, alias = $3, age = $4, identity_card = $5, friends = $6, email = $7 WHERE id = $8", sql)
+
+	sql, err = field.updateSql([]string{"name", "age"})
+	require.NoError(t, err)
+	require.Equal(t, "UPDATE embed_model SET name = $1, age = $2 WHERE id = $3", sql)
+
+	row := []any{1, "t", "n", "a", 2, "c", "f", "e"}
+	require.Equal(t, []any{"n", 2}, field.columnValues(row, []string{"name", "age"}))
+	require.Equal(t, []any{1}, field.columnValues(row, field.PrimaryKeys))
+
+	_, err = field.updateSql([]string{"name", "nickname"})
+	require.EqualError(t, err, "column nickname not found in table embed_model")
+	_, err = field.updateSql([]string{"id"})
+	require.EqualError(t, err, "primary key column id cannot be updated")
+
+	// Values are bound by column name, so the skipped Note field does not
+	// shift name onto email.
+	_, err = db.genCreateTableSql(SkipEmbedModel{})
+	require.NoError(t, err)
+	field, _ = db.GetTableCache("skip_embed_model")
+	require.Equal(t, []string{"id", "email", "name"}, field.Columns)
+	model := &SkipEmbedModel{Id: 7, SkipChild: SkipChild{Note: "n", Email: "e"}, Name: "m"}
+	row, err = buildInsertRow(db, field, reflect.TypeOf(*model), reflect.ValueOf(model))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(7), "e", "m"}, row)
+	require.Equal(t, []any{"e", "m"}, field.columnValues(row, field.updatableColumns()))
+
+	// Unexported fields skipped with db:"-" are never read.
+	_, err = db.genCreateTableSql(UnexportedFieldModel{})
+	require.NoError(t, err)
+	field, _ = db.GetTableCache("unexported_field_model")
+	require.Equal(t, []string{"id", "name"}, field.Columns)
+	unexported := &UnexportedFieldModel{Id: 3, Name: "u"}
+	row, err = buildInsertRow(db, field, reflect.TypeOf(unexported).Elem(), reflect.ValueOf(unexported))
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(3), "u"}, row)
+	require.Equal(t, []any{&unexported.Name}, columnAddrs(db, reflect.ValueOf(unexported), []string{"name"}))
+
+	// Generated columns keep the value the database gave them.
+	_, err = db.genCreateTableSql(GeneratedModel{})
+	require.NoError(t, err)
+	field, _ = db.GetTableCache("generated_model")
+	require.Equal(t, []string{"name"}, field.updatableColumns())
+	sql, err = field.updateSql(field.updatableColumns())
+	require.NoError(t, err)
+	require.Equal(t, "UPDATE generated_model SET name = $1 WHERE id = $2", sql)
+
+	_, err = db.genCreateTableSql(Model{})
+	require.NoError(t, err)
+	field, _ = db.GetTableCache("model")
+	_, err = field.updateSql(field.updatableColumns())
+	require.EqualError(t, err, "table model has no primary key")
+}
+
+func TestUpdate(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(IndexModel{}))
+
+	models := []*IndexModel{
+		{Id: -1, CreateAt: time.Now(), Name: "before", IdentityCard: "card-update-1", JsonColumn: json.RawMessage(`{}`),
+			JsonMap: json.RawMessage(`{}`), Address: netip.MustParseAddr("10.0.0.1")},
+		{Id: -2, CreateAt: time.Now(), Name: "before", IdentityCard: "card-update-2", JsonColumn: json.RawMessage(`{}`),
+			JsonMap: json.RawMessage(`{}`), Address: netip.MustParseAddr("10.0.0.2")},
+	}
+	require.NoError(t, WithTx(db, func(tx Transaction) error {
+		if _, err := tx.Exec("DELETE FROM index_model WHERE id IN (-1, -2)"); err != nil {
+			return err
+		}
+		if err := tx.Insert(models); err != nil {
+			return err
+		}
+
+		for _, model := range models {
+			model.Name = "after"
+		}
+		affected, err := tx.Update(models)
+		require.Equal(t, int64(2), affected)
+		return err
+	}))
+
+	var model IndexModel
+	require.NoError(t, db.FindByPK(&model, int64(-2)))
+	require.Equal(t, "after", model.Name)
+
+	models[0].Name = "partial"
+	models[0].Age = 10
+	affected, err := db.UpdateColumns(models[0], "age")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+	affected, err = db.UpdateFields(models[1], map[string]any{"alias": "partial"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), affected)
+	_, err = db.UpdateFields(models[1], map[string]any{"nickname": "partial"})
+	require.EqualError(t, err, "column nickname not found in table index_model")
+
+	require.NoError(t, db.FindByPK(&model, int64(-1)))
+	require.Equal(t, "after", model.Name)
+	require.Equal(t, 10, model.Age)
+	require.NoError(t, db.FindByPK(&model, int64(-2)))
+	require.Equal(t, "partial", model.Alias)
+}
@@ -0,0 +1,247 @@
+package korm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// upsertCopyThreshold is the number of rows above which Upsert copies the
+// rows into a temporary table and merges them from there.
+const upsertCopyThreshold = 500
+
+// maxQueryParams is the number of bind parameters PostgreSQL accepts in one
+// statement.
+const maxQueryParams = 65535
+
+func (s *DB) Upsert(data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return s.UpsertCtx(context.Background(), data, conflictColumns, updateColumns)
+}
+
+func (s *DB) UpsertCtx(ctx context.Context, data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return upsert(ctx, s, s.conn, data, conflictColumns, updateColumns)
+}
+
+func (tx DBTx) Upsert(data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return tx.UpsertCtx(context.Background(), data, conflictColumns, updateColumns)
+}
+
+func (tx DBTx) UpsertCtx(ctx context.Context, data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	return upsert(ctx, tx.Driver, tx.Tx, data, conflictColumns, updateColumns)
+}
+
+// upsert inserts data, a struct pointer or a slice of them, with
+// INSERT ... ON CONFLICT. conflictColumns defaults to the primary key, or the
+// first unique key when there is none. Rows that conflict are left alone
+// when updateColumns is empty, otherwise those columns are set from
+// EXCLUDED. When updating, rows sharing a non-NULL conflict key are merged
+// into the last of them, since a statement cannot update the same row
+// twice; otherwise PostgreSQL keeps the first of them. It returns the number
+// of rows inserted or updated.
+func upsert(ctx context.Context, d Driver, conn dbConn, data any, conflictColumns []string, updateColumns []string) (int64, error) {
+	t, elems, err := modelValues(data)
+	if err != nil {
+		return 0, err
+	}
+	if len(elems) == 0 {
+		return 0, nil
+	}
+	field, err := lookupField(d, t)
+	if err != nil {
+		return 0, err
+	}
+	target, err := field.conflictTarget(conflictColumns)
+	if err != nil {
+		return 0, err
+	}
+	onConflict, err := field.onConflictSql(target, updateColumns)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([][]any, len(elems))
+	for i, elem := range elems {
+		if rows[i], err = buildInsertRow(d, field, t, elem); err != nil {
+			return 0, err
+		}
+	}
+
+	var affected int64
+	for _, group := range field.insertGroups(rows) {
+		if len(updateColumns) > 0 {
+			group.rows = dedupeRows(group.columns, group.rows, target)
+		}
+		n, err := upsertRows(ctx, d, conn, field.TableName, group.columns, group.rows, onConflict)
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
+
+func upsertRows(ctx context.Context, d Driver, conn dbConn, tableName string, columns []string, rows [][]any, onConflict string) (int64, error) {
+	if len(rows) > upsertCopyThreshold || len(rows)*len(columns) > maxQueryParams {
+		return upsertWithCopy(ctx, d, conn, tableName, columns, rows, onConflict)
+	}
+
+	placeholders := make([]string, len(rows))
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		params := make([]string, len(row))
+		for j := range row {
+			params[j] = fmt.Sprintf("$%d", len(args)+j+1)
+		}
+		placeholders[i] = "(" + strings.Join(params, ", ") + ")"
+		args = append(args, row...)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s %s",
+		tableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "), onConflict)
+	return exec(ctx, d, conn, query, args...)
+}
+
+// upsertWithCopy copies rows into a temporary table and merges it into the
+// target table with a single INSERT ... SELECT ... ON CONFLICT. It runs in
+// its own transaction, or savepoint when conn is already a transaction.
+func upsertWithCopy(ctx context.Context, d Driver, conn dbConn, tableName string, columns []string, rows [][]any, onConflict string) (affected int64, err error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+				err = errors.Join(err, fmt.Errorf("rollback failed: %w", rbErr))
+			}
+		}
+	}()
+
+	tmpTable := "korm_upsert_" + tableName
+	columnList := strings.Join(columns, ", ")
+	// Only the copied columns are taken from the target table, without their
+	// constraints, so columns left out, such as a zero identity, are filled by
+	// the target table's defaults during the merge.
+	createSql := fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA", tmpTable, columnList, tableName)
+	if _, err = exec(ctx, d, tx, createSql); err != nil {
+		return 0, err
+	}
+	if _, err = copyRows(ctx, d, tx, tmpTable, columns, rows); err != nil {
+		return 0, err
+	}
+	mergeSql := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s %s", tableName, columnList, columnList, tmpTable, onConflict)
+	if affected, err = exec(ctx, d, tx, mergeSql); err != nil {
+		return 0, err
+	}
+	if _, err = exec(ctx, d, tx, "DROP TABLE "+tmpTable); err != nil {
+		return 0, err
+	}
+	return affected, tx.Commit(ctx)
+}
+
+// dedupeRows keeps only the last of the rows that share a key, in the
+// position of the first one, since ON CONFLICT DO UPDATE cannot touch the
+// same row twice in one statement. Rows with a NULL key value never conflict
+// and are always kept. Rows are returned unchanged when columns does not hold
+// the whole key, e.g. for rows leaving out an identity key.
+func dedupeRows(columns []string, rows [][]any, key []string) [][]any {
+	idx := make([]int, len(key))
+	for i, column := range key {
+		if idx[i] = slices.Index(columns, column); idx[i] < 0 {
+			return rows
+		}
+	}
+
+	seen := make(map[string]int, len(rows))
+	deduped := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		values := make([]any, len(idx))
+		for i, j := range idx {
+			values[i] = row[j]
+		}
+		k, ok := conflictKey(values)
+		if !ok {
+			deduped = append(deduped, row)
+			continue
+		}
+		if at, ok := seen[k]; ok {
+			deduped[at] = row
+			continue
+		}
+		seen[k] = len(deduped)
+		deduped = append(deduped, row)
+	}
+	return deduped
+}
+
+// conflictKey returns a string that is equal for key values PostgreSQL
+// considers equal. Pointers are followed, driver.Valuer values replaced by
+// their value and times compared as instants. It returns false when a value
+// is NULL, since NULLs never conflict.
+func conflictKey(values []any) (string, bool) {
+	var b strings.Builder
+	for _, value := range values {
+		v := reflect.ValueOf(value)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if !v.IsValid() || v.Kind() == reflect.Ptr {
+			return "", false
+		}
+		value = v.Interface()
+		if valuer, ok := value.(driver.Valuer); ok {
+			var err error
+			if value, err = valuer.Value(); err != nil || value == nil {
+				return "", false
+			}
+		}
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		fmt.Fprintf(&b, "%#v\x00", value)
+	}
+	return b.String(), true
+}
+
+// conflictTarget returns conflictColumns, defaulting to the primary key or
+// else the first unique key.
+func (f *Field) conflictTarget(conflictColumns []string) ([]string, error) {
+	if len(conflictColumns) == 0 {
+		conflictColumns = f.PrimaryKeys
+	}
+	if len(conflictColumns) == 0 && len(f.UniqueKeys) > 0 {
+		conflictColumns = f.UniqueKeys[0]
+	}
+	if len(conflictColumns) == 0 {
+		return nil, fmt.Errorf("table %s has no primary or unique key to detect conflicts", f.TableName)
+	}
+	return conflictColumns, nil
+}
+
+func (f *Field) onConflictSql(conflictColumns []string, updateColumns []string) (string, error) {
+	conflictColumns, err := f.conflictTarget(conflictColumns)
+	if err != nil {
+		return "", err
+	}
+	for _, column := range append(conflictColumns[:len(conflictColumns):len(conflictColumns)], updateColumns...) {
+		if _, ok := f.ColumnMap[column]; !ok {
+			return "", fmt.Errorf("column %s not found in table %s", column, f.TableName)
+		}
+	}
+
+	target := strings.Join(conflictColumns, ", ")
+	if len(updateColumns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", target), nil
+	}
+	sets := make([]string, len(updateColumns))
+	for i, column := range updateColumns {
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", target, strings.Join(sets, ", ")), nil
+}
@@ -0,0 +1,246 @@
+package korm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestField_onConflictSql(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(EmbedModel{})
+	require.NoError(t, err)
+	field, _ := db.GetTableCache("embed_model")
+	require.Equal(t, [][]string{{"identity_card"}}, field.UniqueKeys)
+
+	sql, err := field.onConflictSql(nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "ON CONFLICT (id) DO NOTHING", sql)
+
+	sql, err = field.onConflictSql([]string{"identity_card"}, []string{"name", "age"})
+	require.NoError(t, err)
+	require.Equal(t, "ON CONFLICT (identity_card) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age", sql)
+
+	_, err = field.onConflictSql(nil, []string{"nickname"})
+	require.EqualError(t, err, "column nickname not found in table embed_model")
+
+	_, err = db.genCreateTableSql(Model{})
+	require.NoError(t, err)
+	field, _ = db.GetTableCache("model")
+	_, err = field.onConflictSql(nil, nil)
+	require.EqualError(t, err, "table model has no primary or unique key to detect conflicts")
+}
+
+// recordConn records the statements run on it and on the transactions it
+// begins.
+type recordConn struct {
+	dbConn
+	sqlList []string
+	args    [][]any
+	copies  map[string][][]any
+}
+
+func (c *recordConn) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	c.sqlList = append(c.sqlList, sql)
+	c.args = append(c.args, args)
+	return pgconn.NewCommandTag("INSERT 0 0"), nil
+}
+
+func (c *recordConn) CopyFrom(_ context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	c.sqlList = append(c.sqlList, fmt.Sprintf("COPY %s (%s)", tableName.Sanitize(), strings.Join(columnNames, ", ")))
+	if c.copies == nil {
+		c.copies = make(map[string][][]any)
+	}
+	var n int64
+	for rowSrc.Next() {
+		values, err := rowSrc.Values()
+		if err != nil {
+			return n, err
+		}
+		c.copies[tableName[0]] = append(c.copies[tableName[0]], values)
+		n++
+	}
+	return n, nil
+}
+
+func (c *recordConn) Begin(context.Context) (pgx.Tx, error) {
+	return recordTx{conn: c}, nil
+}
+
+type recordTx struct {
+	pgx.Tx
+	conn *recordConn
+}
+
+func (tx recordTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return tx.conn.Exec(ctx, sql, args...)
+}
+
+func (tx recordTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return tx.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (tx recordTx) Commit(context.Context) error {
+	return nil
+}
+
+func (tx recordTx) Rollback(context.Context) error {
+	return nil
+}
+
+type ExpiringModel struct {
+	Id      int64      `db:"pk"`
+	Expires *time.Time `db:"uk"`
+}
+
+func TestUpsertDuplicateKeys(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(EmbedModel{})
+	require.NoError(t, err)
+
+	conn := &recordConn{}
+	models := []*EmbedModel{
+		{Id: 1, Name: "first", IdentityCard: "card1"},
+		{Id: 2, Name: "other", IdentityCard: "card2"},
+		{Id: 1, Name: "last", IdentityCard: "card1"},
+	}
+	_, err = upsert(context.Background(), db, conn, models, nil, []string{"name"})
+	require.NoError(t, err)
+	require.Len(t, conn.sqlList, 1)
+	require.Equal(t, "INSERT INTO embed_model (id, create_at, name, alias, age, identity_card, friends, email) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8), ($9, $10, $11, $12, $13, $14, $15, $16) "+
+		"ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name", conn.sqlList[0])
+	require.Equal(t, "last", conn.args[0][2])
+	require.Equal(t, "other", conn.args[0][10])
+
+	// The merge from the temporary table gets the same treatment.
+	conn = &recordConn{}
+	unique := upsertCopyThreshold + 10
+	models = make([]*EmbedModel, unique+10)
+	for i := range models {
+		models[i] = &EmbedModel{Id: int64(i % unique), Name: fmt.Sprint(i)}
+	}
+	_, err = upsert(context.Background(), db, conn, models, nil, []string{"name"})
+	require.NoError(t, err)
+	copied := conn.copies["korm_upsert_embed_model"]
+	require.Len(t, copied, unique)
+	require.Equal(t, fmt.Sprint(unique), copied[0][2])
+
+	// With DO NOTHING PostgreSQL keeps the first duplicate itself.
+	conn = &recordConn{}
+	models = []*EmbedModel{{Id: 1, Name: "first"}, {Id: 1, Name: "last"}}
+	_, err = upsert(context.Background(), db, conn, models, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, conn.args[0], 16)
+
+	// NULL keys never conflict, so those rows are all kept.
+	_, err = db.genCreateTableSql(ExpiringModel{})
+	require.NoError(t, err)
+	conn = &recordConn{}
+	expires := time.Now()
+	expiring := []*ExpiringModel{{Id: 1}, {Id: 2}, {Id: 3, Expires: &expires}, {Id: 4}, {Id: 5, Expires: &expires}}
+	_, err = upsert(context.Background(), db, conn, expiring, []string{"expires"}, []string{"id"})
+	require.NoError(t, err)
+	require.Equal(t, []any{int64(1), (*time.Time)(nil), int64(2), (*time.Time)(nil), int64(5), &expires, int64(4), (*time.Time)(nil)}, conn.args[0])
+
+	now := time.Now()
+	key, ok := conflictKey([]any{now, 1})
+	require.True(t, ok)
+	other, _ := conflictKey([]any{now.Round(0).In(time.FixedZone("x", 3600)), 1})
+	require.Equal(t, key, other)
+	key, _ = conflictKey([]any{1, 2})
+	other, _ = conflictKey([]any{2, 1})
+	require.NotEqual(t, key, other)
+	_, ok = conflictKey([]any{1, (*time.Time)(nil)})
+	require.False(t, ok)
+	_, ok = conflictKey([]any{sql.NullString{}})
+	require.False(t, ok)
+}
+
+func TestUpsertCopyIdentity(t *testing.T) {
+	db := initDB(nil)
+	_, err := db.genCreateTableSql(IdentityModel{})
+	require.NoError(t, err)
+
+	conn := &recordConn{}
+	models := make([]*IdentityModel, upsertCopyThreshold+10)
+	for i := range models {
+		models[i] = &IdentityModel{Name: fmt.Sprint(i)}
+	}
+	_, err = upsert(context.Background(), db, conn, models, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"CREATE TEMP TABLE korm_upsert_identity_model ON COMMIT DROP AS SELECT name FROM identity_model WITH NO DATA",
+		`COPY "korm_upsert_identity_model" (name)`,
+		"INSERT INTO identity_model (name) SELECT name FROM korm_upsert_identity_model ON CONFLICT (id) DO NOTHING",
+		"DROP TABLE korm_upsert_identity_model",
+	}, conn.sqlList)
+	require.Len(t, conn.copies["korm_upsert_identity_model"], len(models))
+}
+
+func TestUpsert(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(EmbedModel{}))
+
+	for _, num := range []int{10, upsertCopyThreshold + 10} {
+		models := make([]*EmbedModel, num)
+		for i := range models {
+			models[i] = &EmbedModel{Id: int64(i), CreateAt: time.Now(), Name: "before", IdentityCard: fmt.Sprintf("card%d", i)}
+		}
+		require.NoError(t, WithTx(db, func(tx Transaction) error {
+			if _, err := tx.Exec("DELETE FROM embed_model"); err != nil {
+				return err
+			}
+			if err := tx.Insert(models[:num/2]); err != nil {
+				return err
+			}
+
+			for _, model := range models {
+				model.Name = "after"
+			}
+			affected, err := tx.Upsert(models, nil, nil)
+			require.Equal(t, int64(num-num/2), affected)
+			if err != nil {
+				return err
+			}
+			affected, err = tx.Upsert(models, nil, []string{"name"})
+			require.Equal(t, int64(num), affected)
+			return err
+		}))
+
+		names, err := Select[EmbedModel](db, "SELECT * FROM embed_model WHERE name = $1", "after")
+		require.NoError(t, err)
+		require.Len(t, names, num)
+	}
+}
+
+func TestUpsertIdentity(t *testing.T) {
+	connStr, err := readEnv()
+	require.NoError(t, err)
+	db, err := NewDB(connStr)
+	require.NoError(t, err)
+	require.NoError(t, db.RegisterModels(IdentityModel{}))
+
+	models := make([]*IdentityModel, upsertCopyThreshold+10)
+	for i := range models {
+		models[i] = &IdentityModel{Name: fmt.Sprint(i)}
+	}
+	require.NoError(t, WithTx(db, func(tx Transaction) error {
+		if _, err := tx.Exec("DELETE FROM identity_model"); err != nil {
+			return err
+		}
+		affected, err := tx.Upsert(models, nil, nil)
+		require.Equal(t, int64(len(models)), affected)
+		return err
+	}))
+}